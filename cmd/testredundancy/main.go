@@ -2,10 +2,12 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/toejough/testredundancy"
 )
@@ -19,7 +21,15 @@ func main() {
 
 func run() error {
 	// Parse command line args
-	// Usage: testredundancy [--baseline pkg1,pkg2,...] [--threshold N] [--coverpkg pkgs] <package>
+	// Usage: testredundancy [--baseline pkg1,pkg2,...] [--threshold N] [--coverpkg pkgs]
+	//   [--equivalence] [--cluster N] [--prioritize file] [--budget dur] [--pareto] [--pareto-csv file]
+	//   [--permutations N] [--seed N] [--objective name] [--strict]
+	//   [--override pkg[:Func]=N] [--min-tests N] [--min-tests-override pkg[:Func]=N]
+	//   [--local] [--local-allow pkg1,pkg2,...] [--baseline-gaps] [--undertested] [--json file]
+	//   [--hotspots N] [--closures] [--tags tag1,tag2,...] [--filters name1,name2,...|none]
+	//   [--include-files glob,...] [--exclude-files glob,...] [--include-packages pattern,...]
+	//   [--exclude-packages pattern,...] [--include-generated] [--line-directives]
+	//   [--covermode set|count|atomic] [--intensity] [--lenient-profiles] [--config file.json] <package>
 	args := os.Args[1:]
 
 	config := testredundancy.Config{
@@ -57,6 +67,216 @@ func run() error {
 			}
 			i++
 			config.CoveragePackages = args[i]
+		case "--equivalence":
+			config.ReportEquivalence = true
+		case "--cluster":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--cluster requires an argument")
+			}
+			i++
+			c, err := strconv.ParseFloat(args[i], 64)
+			if err != nil {
+				return fmt.Errorf("invalid cluster threshold: %w", err)
+			}
+			if c <= 0 || c > 1 {
+				return fmt.Errorf("cluster threshold must be in (0, 1]: %v", c)
+			}
+			config.ClusterThreshold = c
+		case "--prioritize":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--prioritize requires an argument")
+			}
+			i++
+			config.PrioritizeFile = args[i]
+		case "--budget":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--budget requires an argument")
+			}
+			i++
+			b, err := time.ParseDuration(args[i])
+			if err != nil {
+				return fmt.Errorf("invalid budget: %w", err)
+			}
+			config.TimeBudget = b
+		case "--pareto":
+			config.ReportPareto = true
+		case "--pareto-csv":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--pareto-csv requires an argument")
+			}
+			i++
+			config.ParetoCSV = args[i]
+		case "--permutations":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--permutations requires an argument")
+			}
+			i++
+			n, err := parseCount(args[i])
+			if err != nil {
+				return fmt.Errorf("invalid permutations: %w", err)
+			}
+			config.Permutations = n
+		case "--seed":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--seed requires an argument")
+			}
+			i++
+			seed, err := strconv.ParseUint(args[i], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid seed: %w", err)
+			}
+			config.Seed = seed
+		case "--objective":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--objective requires an argument")
+			}
+			i++
+			objective, err := testredundancy.ObjectiveByName(args[i])
+			if err != nil {
+				return err
+			}
+			config.Objective = objective
+		case "--strict":
+			config.StrictBlocks = true
+		case "--override":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--override requires an argument")
+			}
+			i++
+			target, t, err := parseOverride(args[i])
+			if err != nil {
+				return err
+			}
+			config.Overrides = append(config.Overrides, testredundancy.Override{
+				Package: target.Package, Function: target.Function, Threshold: t,
+			})
+		case "--min-tests":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--min-tests requires an argument")
+			}
+			i++
+			n, err := parseCount(args[i])
+			if err != nil {
+				return fmt.Errorf("invalid min tests: %w", err)
+			}
+			config.MinTests = n
+		case "--min-tests-override":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--min-tests-override requires an argument")
+			}
+			i++
+			target, n, err := parseOverride(args[i])
+			if err != nil {
+				return err
+			}
+			if n != float64(int(n)) {
+				return fmt.Errorf("invalid min tests override %q: want a whole number", args[i])
+			}
+			config.Overrides = append(config.Overrides, testredundancy.Override{
+				Package: target.Package, Function: target.Function, MinTests: int(n),
+			})
+		case "--local":
+			config.PackageLocal = true
+		case "--local-allow":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--local-allow requires an argument")
+			}
+			i++
+			config.PackageLocal = true
+			for _, pkg := range strings.Split(args[i], ",") {
+				pkg = strings.TrimSpace(pkg)
+				if pkg != "" {
+					config.LocalAllowlist = append(config.LocalAllowlist, pkg)
+				}
+			}
+		case "--baseline-gaps":
+			config.ReportBaselineGaps = true
+		case "--undertested":
+			config.ReportUndertested = true
+		case "--hotspots":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--hotspots requires an argument")
+			}
+			i++
+			n, err := parseCount(args[i])
+			if err != nil {
+				return fmt.Errorf("invalid hotspots: %w", err)
+			}
+			config.ReportHotspots = n
+		case "--closures":
+			config.Closures = true
+		case "--tags":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--tags requires an argument")
+			}
+			i++
+			config.BuildTags = args[i]
+		case "--filters":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--filters requires an argument")
+			}
+			i++
+			config.Filters = []string{}
+			if args[i] != "none" {
+				config.Filters = splitList(args[i])
+			}
+		case "--include-files":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--include-files requires an argument")
+			}
+			i++
+			config.IncludeFiles = append(config.IncludeFiles, splitList(args[i])...)
+		case "--exclude-files":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--exclude-files requires an argument")
+			}
+			i++
+			config.ExcludeFiles = append(config.ExcludeFiles, splitList(args[i])...)
+		case "--include-packages":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--include-packages requires an argument")
+			}
+			i++
+			config.IncludePackages = append(config.IncludePackages, splitList(args[i])...)
+		case "--exclude-packages":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--exclude-packages requires an argument")
+			}
+			i++
+			config.ExcludePackages = append(config.ExcludePackages, splitList(args[i])...)
+		case "--include-generated":
+			config.IncludeGenerated = true
+		case "--line-directives":
+			config.LineDirectives = true
+		case "--covermode":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--covermode requires an argument")
+			}
+			i++
+			config.CoverMode = args[i]
+		case "--intensity":
+			config.ReportIntensity = true
+		case "--lenient-profiles":
+			config.LenientProfiles = true
+		case "--json":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--json requires an argument")
+			}
+			i++
+			config.JSONReport = args[i]
+		case "--config":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--config requires an argument")
+			}
+			i++
+			fc, err := loadFileConfig(args[i])
+			if err != nil {
+				return err
+			}
+			if fc.MinTests != 0 {
+				config.MinTests = fc.MinTests
+			}
+			config.Overrides = append(config.Overrides, fc.Overrides...)
 		default:
 			if strings.HasPrefix(args[i], "-") {
 				return fmt.Errorf("unknown flag: %s", args[i])
@@ -67,3 +287,74 @@ func run() error {
 
 	return testredundancy.Find(config)
 }
+
+// fileConfig is the JSON format of the --config file.
+type fileConfig struct {
+	MinTests  int                       `json:"minTests"`
+	Overrides []testredundancy.Override `json:"overrides"`
+}
+
+// loadFileConfig reads a JSON config file.
+func loadFileConfig(filename string) (fileConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fileConfig{}, fmt.Errorf("failed to read config %s: %w", filename, err)
+	}
+
+	var fc fileConfig
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return fileConfig{}, fmt.Errorf("invalid config %s: %w", filename, err)
+	}
+
+	if fc.MinTests < 0 {
+		return fileConfig{}, fmt.Errorf("invalid config %s: minTests must be non-negative, got %d", filename, fc.MinTests)
+	}
+
+	return fc, nil
+}
+
+// parseOverride parses an override value like "./auth/...=100" or "./auth:Check=100" into the
+// override's target (package and function only) and its value.
+func parseOverride(spec string) (testredundancy.Override, float64, error) {
+	target, value, ok := strings.Cut(spec, "=")
+	if !ok {
+		return testredundancy.Override{}, 0, fmt.Errorf("invalid override %q: want pkg[:Func]=N", spec)
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return testredundancy.Override{}, 0, fmt.Errorf("invalid override value %q: %w", spec, err)
+	}
+
+	pkg, fn, _ := strings.Cut(target, ":")
+
+	return testredundancy.Override{Package: pkg, Function: fn}, n, nil
+}
+
+// parseCount parses a flag value that counts something, so must not be negative.
+func parseCount(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+
+	if n < 0 {
+		return 0, fmt.Errorf("must be non-negative, got %d", n)
+	}
+
+	return n, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
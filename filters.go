@@ -0,0 +1,66 @@
+package testredundancy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+// DefaultFilters are the built-in coverage filters applied when Config.Filters is nil.
+// Under Config.LineDirectives, template coverage is analyzed, so "qtpl" is left out.
+var DefaultFilters = []string{"qtpl"}
+
+// profileFilter builds the filter applied to every coverage profile read: the built-in
+// filters, then file glob and package pattern includes and excludes.
+func profileFilter(config Config) (coverage.Filter, error) {
+	names := config.Filters
+	if names == nil {
+		for _, name := range DefaultFilters {
+			if name != "qtpl" || !config.LineDirectives {
+				names = append(names, name)
+			}
+		}
+	}
+
+	var filters []coverage.Filter
+
+	for _, name := range names {
+		filter, ok := coverage.BuiltinFilters[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown filter %q (valid: %s)", name, strings.Join(coverage.BuiltinFilterNames(), ", "))
+		}
+
+		filters = append(filters, filter)
+	}
+
+	for _, glob := range append(append([]string{}, config.IncludeFiles...), config.ExcludeFiles...) {
+		if err := coverage.ValidateGlob(glob); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(config.IncludeFiles) > 0 {
+		filters = append(filters, coverage.IncludeFiles(config.IncludeFiles...))
+	}
+
+	if len(config.ExcludeFiles) > 0 {
+		filters = append(filters, coverage.ExcludeFiles(config.ExcludeFiles...))
+	}
+
+	for _, pattern := range append(append([]string{}, config.IncludePackages...), config.ExcludePackages...) {
+		if err := coverage.ValidatePackage(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(config.IncludePackages) > 0 {
+		filters = append(filters, coverage.IncludePackages(config.IncludePackages...))
+	}
+
+	if len(config.ExcludePackages) > 0 {
+		filters = append(filters, coverage.ExcludePackages(config.ExcludePackages...))
+	}
+
+	return coverage.AllFilters(filters...), nil
+}
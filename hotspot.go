@@ -0,0 +1,65 @@
+package testredundancy
+
+import (
+	"sort"
+)
+
+// hotspot is a function covered by many tests, most of which may be redundant.
+type hotspot struct {
+	function  string
+	tests     int // Tests covering the function at all
+	kept      int // Kept tests covering the function
+	redundant int // Redundant tests covering the function
+}
+
+// factor is the coverage redundancy factor: covering tests per kept covering test.
+func (h hotspot) factor() float64 {
+	return float64(h.tests) / float64(max(1, h.kept))
+}
+
+// hotspots ranks functions by how many tests cover them, then by how many of those are
+// redundant, returning at most limit entries.
+func (s *selector) hotspots(kept map[string]bool, limit int) []hotspot {
+	byFunc := make(map[string]*hotspot)
+
+	for _, qName := range sortedKeys(s.blockSets) {
+		for fn := range s.functionsCoveredBy(qName) {
+			h := byFunc[fn]
+			if h == nil {
+				h = &hotspot{function: fn}
+				byFunc[fn] = h
+			}
+
+			h.tests++
+
+			if kept[qName] {
+				h.kept++
+			} else {
+				h.redundant++
+			}
+		}
+	}
+
+	result := make([]hotspot, 0, len(byFunc))
+	for _, h := range byFunc {
+		result = append(result, *h)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].tests != result[j].tests {
+			return result[i].tests > result[j].tests
+		}
+
+		if result[i].redundant != result[j].redundant {
+			return result[i].redundant > result[j].redundant
+		}
+
+		return result[i].function < result[j].function
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result
+}
@@ -0,0 +1,73 @@
+package testredundancy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+func TestHotspots(t *testing.T) {
+	funcMap := testFuncMap("F", "G", "H", "I", "J")
+	s := &selector{
+		funcMap: funcMap,
+		blockSets: map[string]*coverage.BlockSet{
+			"pkg:TestFG":  testBlockSet(50, 1, 11),
+			"pkg:TestFG2": testBlockSet(50, 2, 12),
+			"pkg:TestFHJ": testBlockSet(50, 3, 21, 41),
+			"pkg:TestI":   testBlockSet(50, 31),
+		},
+		thresholds: testResolver(80, 1, funcMap),
+		objective:  FunctionsObjective{},
+	}
+	kept := map[string]bool{"pkg:TestFG": true, "pkg:TestI": true}
+
+	all := []hotspot{
+		{function: testFile + ":F", tests: 3, kept: 1, redundant: 2},
+		{function: testFile + ":G", tests: 2, kept: 1, redundant: 1},
+		// Same test and redundant counts: by name
+		{function: testFile + ":H", tests: 1, kept: 0, redundant: 1},
+		{function: testFile + ":J", tests: 1, kept: 0, redundant: 1},
+		// Fewer redundant tests rank lower
+		{function: testFile + ":I", tests: 1, kept: 1, redundant: 0},
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []hotspot
+	}{
+		{name: "limit truncates", limit: 2, want: all[:2]},
+		{name: "limit beyond functions", limit: 10, want: all},
+		{name: "zero limit", limit: 0, want: []hotspot{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.hotspots(kept, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hotspots(%d) = %+v, want %+v", tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHotspotFactor(t *testing.T) {
+	tests := []struct {
+		name string
+		h    hotspot
+		want float64
+	}{
+		{name: "tests per kept test", h: hotspot{tests: 6, kept: 2}, want: 3},
+		{name: "one kept test", h: hotspot{tests: 4, kept: 1}, want: 4},
+		{name: "no kept test counts as one", h: hotspot{tests: 3, kept: 0}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.h.factor(); got != tt.want {
+				t.Errorf("factor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
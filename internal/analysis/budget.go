@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"sort"
+	"time"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+// minDuration is the runtime assumed for tests measured at zero, so gain-per-second stays finite.
+const minDuration = time.Millisecond
+
+// BudgetSelect picks tests whose combined duration fits within budget, maximizing score.
+// score reports the value of merged coverage (e.g., target functions at threshold).
+// Each step adds the test with the best score gain per second of runtime, breaking ties
+// (including the all-zero case, where no single test moves the score) by newly covered
+// statements per second, so the suite keeps growing toward the score threshold. Since that ratio
+// rule alone can miss a single expensive but valuable test, the best single test that fits is
+// checked too; if it scores higher than the greedy suite, selection restarts from it and fills
+// the remaining budget the same way.
+func BudgetSelect(
+	names []string,
+	sets map[string]*coverage.BlockSet,
+	durations map[string]time.Duration,
+	budget time.Duration,
+	score func(*coverage.BlockSet) int,
+) []string {
+	candidates := append([]string(nil), names...)
+	sort.Strings(candidates)
+
+	selected, selectedScore := budgetGreedy(candidates, sets, durations, budget, score, nil)
+
+	// Compare with the best single test that fits
+	bestSingle := ""
+	bestSingleScore := score(&coverage.BlockSet{Blocks: make(map[string]coverage.BlockInfo)})
+
+	for _, name := range candidates {
+		bs := sets[name]
+		if bs == nil || durations[name] > budget {
+			continue
+		}
+
+		if s := score(bs); s > bestSingleScore {
+			bestSingle = name
+			bestSingleScore = s
+		}
+	}
+
+	if bestSingle != "" && bestSingleScore > selectedScore {
+		selected, _ = budgetGreedy(candidates, sets, durations, budget, score, []string{bestSingle})
+	}
+
+	return selected
+}
+
+// budgetGreedy adds tests to seed by best score gain per second (see BudgetSelect) until no
+// remaining test fits the budget and improves coverage. Returns the suite and its score.
+func budgetGreedy(
+	candidates []string,
+	sets map[string]*coverage.BlockSet,
+	durations map[string]time.Duration,
+	budget time.Duration,
+	score func(*coverage.BlockSet) int,
+	seed []string,
+) ([]string, int) {
+	current := &coverage.BlockSet{Blocks: make(map[string]coverage.BlockInfo)}
+	selected := append([]string(nil), seed...)
+	used := make(map[string]bool)
+
+	var spent time.Duration
+
+	for _, name := range seed {
+		used[name] = true
+		spent += durations[name]
+		current.Merge(sets[name])
+	}
+
+	currentScore := score(current)
+
+	for {
+		bestName := ""
+		bestRatio := 0.0
+		bestStmtRatio := 0.0
+		bestScore := currentScore
+
+		for _, name := range candidates {
+			bs := sets[name]
+			if used[name] || bs == nil || spent+durations[name] > budget {
+				continue
+			}
+
+			merged := current.Clone()
+			merged.Merge(bs)
+			mergedScore := score(merged)
+
+			gain := mergedScore - currentScore
+			stmtGain := current.CountNewStatements(bs)
+			if gain < 0 || (gain == 0 && stmtGain == 0) {
+				continue
+			}
+
+			seconds := max(durations[name], minDuration).Seconds()
+			ratio := float64(gain) / seconds
+			stmtRatio := float64(stmtGain) / seconds
+
+			if ratio > bestRatio || (ratio == bestRatio && stmtRatio > bestStmtRatio) {
+				bestName = name
+				bestRatio = ratio
+				bestStmtRatio = stmtRatio
+				bestScore = mergedScore
+			}
+		}
+
+		if bestName == "" {
+			return selected, currentScore
+		}
+
+		selected = append(selected, bestName)
+		used[bestName] = true
+		spent += durations[bestName]
+		current.Merge(sets[bestName])
+		currentScore = bestScore
+	}
+}
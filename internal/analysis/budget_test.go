@@ -0,0 +1,98 @@
+package analysis_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/toejough/testredundancy/internal/analysis"
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+func TestBudgetSelect(t *testing.T) {
+	sets := map[string]*coverage.BlockSet{
+		"pkg:TestCheap":     blockSet("f.go:1.1,2.1"),
+		"pkg:TestCheapToo":  blockSet("f.go:3.1,4.1"),
+		"pkg:TestExpensive": blockSet("f.go:1.1,2.1", "f.go:3.1,4.1", "f.go:5.1,6.1", "f.go:7.1,8.1"),
+		"pkg:TestExtra":     blockSet("f.go:9.1,10.1"),
+		"pkg:TestUseless":   blockSet("f.go:1.1,2.1"),
+	}
+	durations := map[string]time.Duration{
+		"pkg:TestCheap":     1 * time.Second,
+		"pkg:TestCheapToo":  1 * time.Second,
+		"pkg:TestExpensive": 10 * time.Second,
+		"pkg:TestExtra":     500 * time.Millisecond,
+		"pkg:TestUseless":   100 * time.Millisecond,
+	}
+	names := []string{"pkg:TestCheap", "pkg:TestCheapToo", "pkg:TestExpensive", "pkg:TestExtra", "pkg:TestUseless"}
+
+	score := func(bs *coverage.BlockSet) int {
+		return len(blockSet().NewBlocksFrom(bs))
+	}
+
+	tests := []struct {
+		name   string
+		budget time.Duration
+		want   []string
+	}{
+		{
+			name:   "ratio greedy within budget",
+			budget: 3 * time.Second,
+			want:   []string{"pkg:TestUseless", "pkg:TestExtra", "pkg:TestCheapToo"},
+		},
+		{
+			name:   "single expensive test beats greedy",
+			budget: 10 * time.Second,
+			want:   []string{"pkg:TestExpensive"},
+		},
+		{
+			name:   "greedy continues from the single expensive test",
+			budget: 11 * time.Second,
+			want:   []string{"pkg:TestExpensive", "pkg:TestExtra"},
+		},
+		{
+			name:   "nothing fits",
+			budget: 50 * time.Millisecond,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := analysis.BudgetSelect(names, sets, durations, tt.budget, score)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BudgetSelect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBudgetSelectStatementGain(t *testing.T) {
+	// No single test reaches the score threshold (two of the three blocks), so only statement
+	// gain can move the greedy selection forward
+	sets := map[string]*coverage.BlockSet{
+		"pkg:TestA": blockSet("f.go:1.1,2.1"),
+		"pkg:TestB": blockSet("f.go:3.1,4.1"),
+		"pkg:TestC": blockSet("f.go:1.1,2.1"),
+	}
+	durations := map[string]time.Duration{
+		"pkg:TestA": 1 * time.Second,
+		"pkg:TestB": 2 * time.Second,
+		"pkg:TestC": 1 * time.Second,
+	}
+	names := []string{"pkg:TestA", "pkg:TestB", "pkg:TestC"}
+
+	score := func(bs *coverage.BlockSet) int {
+		if bs.CoveredBlockCount() >= 2 {
+			return 1
+		}
+		return 0
+	}
+
+	got := analysis.BudgetSelect(names, sets, durations, 5*time.Second, score)
+	want := []string{"pkg:TestA", "pkg:TestB"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BudgetSelect() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,86 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+// Cluster is a group of tests in one package with highly similar coverage.
+type Cluster struct {
+	Package string
+	Tests   []string // Qualified test names, sorted
+}
+
+// Clusters groups tests within each package whose coverage similarity meets threshold.
+// Clustering is single-linkage: a test joins a cluster if it is similar enough to any member.
+// Only clusters with two or more members are returned, largest first.
+func Clusters(sets map[string]*coverage.BlockSet, threshold float64) []Cluster {
+	byPkg := make(map[string][]string)
+	for name := range sets {
+		pkg := packageOf(name)
+		byPkg[pkg] = append(byPkg[pkg], name)
+	}
+
+	var clusters []Cluster
+
+	for pkg, names := range byPkg {
+		sort.Strings(names)
+
+		// Union-find over test indexes
+		parent := make([]int, len(names))
+		for i := range parent {
+			parent[i] = i
+		}
+
+		var find func(int) int
+		find = func(i int) int {
+			if parent[i] != i {
+				parent[i] = find(parent[i])
+			}
+
+			return parent[i]
+		}
+
+		for i := range names {
+			for j := i + 1; j < len(names); j++ {
+				if sets[names[i]].Jaccard(sets[names[j]]) >= threshold {
+					parent[find(j)] = find(i)
+				}
+			}
+		}
+
+		members := make(map[int][]string)
+		for i, name := range names {
+			root := find(i)
+			members[root] = append(members[root], name)
+		}
+
+		for _, tests := range members {
+			if len(tests) > 1 {
+				clusters = append(clusters, Cluster{Package: pkg, Tests: tests})
+			}
+		}
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i].Tests) != len(clusters[j].Tests) {
+			return len(clusters[i].Tests) > len(clusters[j].Tests)
+		}
+
+		return clusters[i].Tests[0] < clusters[j].Tests[0]
+	})
+
+	return clusters
+}
+
+// packageOf returns the package part of a qualified test name (pkg:TestName).
+func packageOf(qualifiedName string) string {
+	idx := strings.LastIndex(qualifiedName, ":")
+	if idx < 0 {
+		return ""
+	}
+
+	return qualifiedName[:idx]
+}
@@ -0,0 +1,72 @@
+package analysis_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/toejough/testredundancy/internal/analysis"
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+func TestJaccard(t *testing.T) {
+	tests := []struct {
+		name string
+		a    *coverage.BlockSet
+		b    *coverage.BlockSet
+		want float64
+	}{
+		{
+			name: "identical",
+			a:    blockSet("f.go:1.1,2.1", "f.go:3.1,4.1"),
+			b:    blockSet("f.go:1.1,2.1", "f.go:3.1,4.1"),
+			want: 1,
+		},
+		{
+			name: "half overlap",
+			a:    blockSet("f.go:1.1,2.1", "f.go:3.1,4.1"),
+			b:    blockSet("f.go:1.1,2.1", "f.go:3.1,4.1", "f.go:5.1,6.1", "f.go:7.1,8.1"),
+			want: 0.5,
+		},
+		{
+			name: "disjoint",
+			a:    blockSet("f.go:1.1,2.1"),
+			b:    blockSet("f.go:3.1,4.1"),
+			want: 0,
+		},
+		{
+			name: "both empty",
+			a:    blockSet(),
+			b:    blockSet(),
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Jaccard(tt.b); got != tt.want {
+				t.Errorf("Jaccard() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusters(t *testing.T) {
+	sets := map[string]*coverage.BlockSet{
+		// A~B and B~C chain into one cluster; D is dissimilar
+		"pkg/a:TestA": blockSet("f.go:1.1,2.1", "f.go:3.1,4.1", "f.go:5.1,6.1"),
+		"pkg/a:TestB": blockSet("f.go:1.1,2.1", "f.go:3.1,4.1", "f.go:5.1,6.1", "f.go:7.1,8.1"),
+		"pkg/a:TestC": blockSet("f.go:3.1,4.1", "f.go:5.1,6.1", "f.go:7.1,8.1"),
+		"pkg/a:TestD": blockSet("f.go:9.1,10.1"),
+		// Identical to TestA but in another package
+		"pkg/b:TestE": blockSet("f.go:1.1,2.1", "f.go:3.1,4.1", "f.go:5.1,6.1"),
+	}
+
+	got := analysis.Clusters(sets, 0.75)
+	want := []analysis.Cluster{
+		{Package: "pkg/a", Tests: []string{"pkg/a:TestA", "pkg/a:TestB", "pkg/a:TestC"}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Clusters() = %v, want %v", got, want)
+	}
+}
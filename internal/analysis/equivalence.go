@@ -0,0 +1,168 @@
+// Package analysis provides coverage relationship analyses over per-test BlockSets.
+package analysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+// Class is a group of tests whose covered blocks are identical.
+type Class struct {
+	Fingerprint string
+	Tests       []string // Qualified test names, sorted
+}
+
+// Subsumption records that Subset covers a strict subset of the blocks Superset covers.
+type Subsumption struct {
+	Subset   string
+	Superset string
+}
+
+// Fingerprint returns a stable fingerprint of the blocks covered in bs.
+func Fingerprint(bs *coverage.BlockSet) string {
+	h := sha256.New()
+
+	for _, blockID := range coveredBlocks(bs) {
+		h.Write([]byte(blockID))
+		h.Write([]byte{'\n'})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// RunFingerprint returns a stable fingerprint of an analysis run's inputs: the settings string
+// plus each test's name and coverage fingerprint. Test durations are not included, so runs with equal
+// fingerprints agree on coverage-derived results (kept and redundant tests, equivalence, clusters) but
+// may differ in duration-driven ones such as the budget suite and prioritized order.
+func RunFingerprint(sets map[string]*coverage.BlockSet, settings string) string {
+	names := make([]string, 0, len(sets))
+	for name := range sets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	h := sha256.New()
+	h.Write([]byte(settings))
+	h.Write([]byte{'\n'})
+
+	for _, name := range names {
+		h.Write([]byte(name + " " + Fingerprint(sets[name])))
+		h.Write([]byte{'\n'})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// EquivalenceClasses groups tests with identical coverage fingerprints.
+// Only classes with two or more members are returned, largest first.
+func EquivalenceClasses(sets map[string]*coverage.BlockSet) []Class {
+	var classes []Class
+
+	for _, class := range groupByFingerprint(sets) {
+		if len(class.Tests) > 1 {
+			classes = append(classes, class)
+		}
+	}
+
+	sort.Slice(classes, func(i, j int) bool {
+		if len(classes[i].Tests) != len(classes[j].Tests) {
+			return len(classes[i].Tests) > len(classes[j].Tests)
+		}
+
+		return classes[i].Tests[0] < classes[j].Tests[0]
+	})
+
+	return classes
+}
+
+// Subsumptions lists pairs of tests where one covers a strict subset of the other's blocks.
+// Coverage is compared once per equivalence class, and a pair is listed for every member of
+// each class. Tests covering nothing are skipped since every other test trivially subsumes them.
+func Subsumptions(sets map[string]*coverage.BlockSet) []Subsumption {
+	type rep struct {
+		members []string
+		blocks  *coverage.BlockSet
+		covered int
+	}
+
+	var reps []rep
+
+	for _, class := range groupByFingerprint(sets) {
+		bs := sets[class.Tests[0]]
+
+		covered := bs.CoveredBlockCount()
+		if covered == 0 {
+			continue
+		}
+
+		reps = append(reps, rep{members: class.Tests, blocks: bs, covered: covered})
+	}
+
+	var pairs []Subsumption
+
+	for _, a := range reps {
+		for _, b := range reps {
+			if a.covered >= b.covered || !a.blocks.IsSubsetOf(b.blocks) {
+				continue
+			}
+
+			for _, subset := range a.members {
+				for _, superset := range b.members {
+					pairs = append(pairs, Subsumption{Subset: subset, Superset: superset})
+				}
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Subset != pairs[j].Subset {
+			return pairs[i].Subset < pairs[j].Subset
+		}
+
+		return pairs[i].Superset < pairs[j].Superset
+	})
+
+	return pairs
+}
+
+// groupByFingerprint returns every fingerprint class, including singletons.
+func groupByFingerprint(sets map[string]*coverage.BlockSet) []Class {
+	byFingerprint := make(map[string][]string)
+
+	for name, bs := range sets {
+		fp := Fingerprint(bs)
+		byFingerprint[fp] = append(byFingerprint[fp], name)
+	}
+
+	classes := make([]Class, 0, len(byFingerprint))
+
+	for fp, tests := range byFingerprint {
+		sort.Strings(tests)
+		classes = append(classes, Class{Fingerprint: fp, Tests: tests})
+	}
+
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].Tests[0] < classes[j].Tests[0]
+	})
+
+	return classes
+}
+
+// coveredBlocks returns the sorted IDs of covered blocks in bs.
+func coveredBlocks(bs *coverage.BlockSet) []string {
+	var blockIDs []string
+
+	for blockID, info := range bs.Blocks {
+		if info.Covered {
+			blockIDs = append(blockIDs, blockID)
+		}
+	}
+
+	sort.Strings(blockIDs)
+
+	return blockIDs
+}
@@ -0,0 +1,114 @@
+package analysis_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/toejough/testredundancy/internal/analysis"
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+// blockSet builds a BlockSet with the given covered block IDs, one statement each.
+func blockSet(covered ...string) *coverage.BlockSet {
+	bs := &coverage.BlockSet{Blocks: make(map[string]coverage.BlockInfo)}
+	for _, blockID := range covered {
+		bs.Blocks[blockID] = coverage.BlockInfo{Statements: 1, Covered: true}
+	}
+
+	return bs
+}
+
+func TestFingerprint(t *testing.T) {
+	a := blockSet("f.go:1.1,2.1", "f.go:3.1,4.1")
+	b := blockSet("f.go:3.1,4.1", "f.go:1.1,2.1")
+	b.Blocks["f.go:5.1,6.1"] = coverage.BlockInfo{Statements: 2, Covered: false}
+	c := blockSet("f.go:1.1,2.1")
+
+	if analysis.Fingerprint(a) != analysis.Fingerprint(b) {
+		t.Error("uncovered blocks and insertion order should not affect the fingerprint")
+	}
+
+	if analysis.Fingerprint(a) == analysis.Fingerprint(c) {
+		t.Error("different covered blocks should produce different fingerprints")
+	}
+}
+
+func TestRunFingerprint(t *testing.T) {
+	sets := map[string]*coverage.BlockSet{
+		"pkg:TestA": blockSet("f.go:1.1,2.1"),
+		"pkg:TestB": blockSet("f.go:3.1,4.1"),
+	}
+	same := map[string]*coverage.BlockSet{
+		"pkg:TestB": blockSet("f.go:3.1,4.1"),
+		"pkg:TestA": blockSet("f.go:1.1,2.1"),
+	}
+	renamed := map[string]*coverage.BlockSet{
+		"pkg:TestA": blockSet("f.go:1.1,2.1"),
+		"pkg:TestC": blockSet("f.go:3.1,4.1"),
+	}
+
+	fp := analysis.RunFingerprint(sets, "threshold=80")
+
+	if got := analysis.RunFingerprint(same, "threshold=80"); got != fp {
+		t.Errorf("equal inputs gave different fingerprints: %s vs %s", got, fp)
+	}
+
+	if analysis.RunFingerprint(renamed, "threshold=80") == fp {
+		t.Error("renamed test should change the fingerprint")
+	}
+
+	if analysis.RunFingerprint(sets, "threshold=90") == fp {
+		t.Error("different settings should change the fingerprint")
+	}
+}
+
+func TestEquivalenceClasses(t *testing.T) {
+	sets := map[string]*coverage.BlockSet{
+		"pkg:TestA": blockSet("f.go:1.1,2.1"),
+		"pkg:TestB": blockSet("f.go:1.1,2.1"),
+		"pkg:TestC": blockSet("f.go:1.1,2.1", "f.go:3.1,4.1"),
+		"pkg:TestD": blockSet("f.go:3.1,4.1"),
+		"pkg:TestE": blockSet("f.go:3.1,4.1"),
+		"pkg:TestF": blockSet("f.go:3.1,4.1"),
+	}
+
+	classes := analysis.EquivalenceClasses(sets)
+
+	var got [][]string
+	for _, class := range classes {
+		got = append(got, class.Tests)
+	}
+
+	want := [][]string{
+		{"pkg:TestD", "pkg:TestE", "pkg:TestF"},
+		{"pkg:TestA", "pkg:TestB"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EquivalenceClasses() = %v, want %v", got, want)
+	}
+}
+
+func TestSubsumptions(t *testing.T) {
+	sets := map[string]*coverage.BlockSet{
+		"pkg:TestA":     blockSet("f.go:1.1,2.1"),
+		"pkg:TestB":     blockSet("f.go:1.1,2.1"),
+		"pkg:TestC":     blockSet("f.go:1.1,2.1", "f.go:3.1,4.1"),
+		"pkg:TestC2":    blockSet("f.go:1.1,2.1", "f.go:3.1,4.1"),
+		"pkg:TestD":     blockSet("f.go:3.1,4.1", "f.go:5.1,6.1"),
+		"pkg:TestEmpty": blockSet(),
+	}
+
+	// TestA and TestB are equivalent, as are TestC and TestC2, so each pairing is listed
+	got := analysis.Subsumptions(sets)
+	want := []analysis.Subsumption{
+		{Subset: "pkg:TestA", Superset: "pkg:TestC"},
+		{Subset: "pkg:TestA", Superset: "pkg:TestC2"},
+		{Subset: "pkg:TestB", Superset: "pkg:TestC"},
+		{Subset: "pkg:TestB", Superset: "pkg:TestC2"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Subsumptions() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+// Intensity summarizes how hard a test exercises the code it covers, from count-mode hit counts.
+type Intensity struct {
+	Name           string
+	CoveredBlocks  int
+	Hits           int // Total block executions
+	MaxHits        int // Most executions of a single block
+	RepeatedBlocks int // Covered blocks executed more than once (e.g., loop bodies)
+	StressBlocks   int // Blocks this test executes more often than any other test does
+}
+
+// MeanHits returns the average executions per covered block, or 0 if nothing is covered.
+func (i Intensity) MeanHits() float64 {
+	if i.CoveredBlocks == 0 {
+		return 0
+	}
+
+	return float64(i.Hits) / float64(i.CoveredBlocks)
+}
+
+// Intensities measures each test's execution intensity, ordered by mean hits (descending),
+// then name. A block counts toward a test's StressBlocks when the test runs it more than
+// once and strictly more often than every other test, so tests that merely pass through a
+// loop are told apart from those that stress it.
+func Intensities(sets map[string]*coverage.BlockSet) []Intensity {
+	names := make([]string, 0, len(sets))
+	for name := range sets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	// Highest and runner-up hit counts per block, to find a strict leader
+	type leader struct {
+		name     string
+		hits     int
+		runnerUp int
+	}
+
+	leaders := make(map[string]*leader)
+	result := make([]Intensity, 0, len(names))
+
+	for _, name := range names {
+		in := Intensity{Name: name}
+
+		for blockID, info := range sets[name].Blocks {
+			if !info.Covered {
+				continue
+			}
+
+			hits := max(info.Count, 1)
+			in.CoveredBlocks++
+			in.Hits += hits
+			in.MaxHits = max(in.MaxHits, hits)
+
+			if hits > 1 {
+				in.RepeatedBlocks++
+			}
+
+			l := leaders[blockID]
+			switch {
+			case l == nil:
+				leaders[blockID] = &leader{name: name, hits: hits}
+			case hits > l.hits:
+				l.name, l.hits, l.runnerUp = name, hits, l.hits
+			case hits > l.runnerUp:
+				l.runnerUp = hits
+			}
+		}
+
+		result = append(result, in)
+	}
+
+	stress := make(map[string]int)
+	for _, l := range leaders {
+		if l.hits > 1 && l.hits > l.runnerUp {
+			stress[l.name]++
+		}
+	}
+
+	for i := range result {
+		result[i].StressBlocks = stress[result[i].Name]
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].MeanHits() > result[j].MeanHits()
+	})
+
+	return result
+}
@@ -0,0 +1,61 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/toejough/testredundancy/internal/analysis"
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+// countBlockSet builds a count-mode BlockSet from block ID -> hit count, one statement each.
+func countBlockSet(hits map[string]int) *coverage.BlockSet {
+	bs := &coverage.BlockSet{Blocks: make(map[string]coverage.BlockInfo), Mode: coverage.ModeCount}
+	for blockID, count := range hits {
+		bs.Blocks[blockID] = coverage.BlockInfo{Statements: 1, Covered: count > 0, Count: count}
+	}
+
+	return bs
+}
+
+func TestIntensities(t *testing.T) {
+	sets := map[string]*coverage.BlockSet{
+		"pkg:TestOnce":   countBlockSet(map[string]int{"f.go:1.1,2.1": 1, "f.go:3.1,4.1": 1, "f.go:5.1,6.1": 0}),
+		"pkg:TestStress": countBlockSet(map[string]int{"f.go:1.1,2.1": 1, "f.go:3.1,4.1": 100}),
+		"pkg:TestTwice":  countBlockSet(map[string]int{"f.go:3.1,4.1": 2, "f.go:7.1,8.1": 2}),
+	}
+
+	got := analysis.Intensities(sets)
+
+	want := []analysis.Intensity{
+		{Name: "pkg:TestStress", CoveredBlocks: 2, Hits: 101, MaxHits: 100, RepeatedBlocks: 1, StressBlocks: 1},
+		{Name: "pkg:TestTwice", CoveredBlocks: 2, Hits: 4, MaxHits: 2, RepeatedBlocks: 2, StressBlocks: 1},
+		{Name: "pkg:TestOnce", CoveredBlocks: 2, Hits: 2, MaxHits: 1},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Intensities() returned %d entries, want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Intensities()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if mean := got[0].MeanHits(); mean != 50.5 {
+		t.Errorf("MeanHits() = %v, want 50.5", mean)
+	}
+}
+
+func TestIntensitiesTiedLeadersDoNotStress(t *testing.T) {
+	sets := map[string]*coverage.BlockSet{
+		"pkg:TestA": countBlockSet(map[string]int{"f.go:1.1,2.1": 5}),
+		"pkg:TestB": countBlockSet(map[string]int{"f.go:1.1,2.1": 5}),
+	}
+
+	for _, in := range analysis.Intensities(sets) {
+		if in.StressBlocks != 0 {
+			t.Errorf("%s StressBlocks = %d, want 0 for a tie", in.Name, in.StressBlocks)
+		}
+	}
+}
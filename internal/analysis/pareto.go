@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"time"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+// CurvePoint is the cumulative cost and benefit of running a prefix of a test order.
+type CurvePoint struct {
+	Test            string        // Last test in the prefix
+	Runtime         time.Duration // Cumulative runtime of the prefix
+	Score           int           // Score of the prefix's merged coverage (e.g., functions at threshold)
+	CoveragePercent float64       // Statement coverage of the prefix's merged coverage
+	Frontier        bool          // Whether the prefix improves on every cheaper prefix
+}
+
+// Curve computes the coverage-vs-runtime tradeoff for each prefix of order.
+// totalStatements is the denominator for CoveragePercent, normally the full suite's total.
+// Since both metrics only grow along the order, a prefix is on the Pareto frontier
+// exactly when it improves score or coverage over the prefix before it.
+func Curve(
+	order []string,
+	sets map[string]*coverage.BlockSet,
+	durations map[string]time.Duration,
+	totalStatements int,
+	score func(*coverage.BlockSet) int,
+) []CurvePoint {
+	current := &coverage.BlockSet{Blocks: make(map[string]coverage.BlockInfo)}
+	prevScore := score(current)
+	prevCovered := 0
+
+	var runtime time.Duration
+
+	points := make([]CurvePoint, 0, len(order))
+
+	for _, name := range order {
+		if bs := sets[name]; bs != nil {
+			current.Merge(bs)
+		}
+
+		runtime += durations[name]
+		s := score(current)
+		covered := current.CoveredStatements()
+
+		percent := 0.0
+		if totalStatements > 0 {
+			percent = float64(covered) * 100.0 / float64(totalStatements)
+		}
+
+		points = append(points, CurvePoint{
+			Test:            name,
+			Runtime:         runtime,
+			Score:           s,
+			CoveragePercent: percent,
+			Frontier:        s > prevScore || covered > prevCovered,
+		})
+
+		prevScore = s
+		prevCovered = covered
+	}
+
+	return points
+}
@@ -0,0 +1,38 @@
+package analysis_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/toejough/testredundancy/internal/analysis"
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+func TestCurve(t *testing.T) {
+	sets := map[string]*coverage.BlockSet{
+		"pkg:TestA": blockSet("f.go:1.1,2.1", "f.go:3.1,4.1"),
+		"pkg:TestB": blockSet("f.go:1.1,2.1"),
+		"pkg:TestC": blockSet("f.go:5.1,6.1"),
+	}
+	durations := map[string]time.Duration{
+		"pkg:TestA": 2 * time.Second,
+		"pkg:TestB": 1 * time.Second,
+		"pkg:TestC": 3 * time.Second,
+	}
+
+	score := func(bs *coverage.BlockSet) int {
+		return bs.CoveredStatements()
+	}
+
+	got := analysis.Curve([]string{"pkg:TestA", "pkg:TestB", "pkg:TestC"}, sets, durations, 4, score)
+	want := []analysis.CurvePoint{
+		{Test: "pkg:TestA", Runtime: 2 * time.Second, Score: 2, CoveragePercent: 50, Frontier: true},
+		{Test: "pkg:TestB", Runtime: 3 * time.Second, Score: 2, CoveragePercent: 50, Frontier: false},
+		{Test: "pkg:TestC", Runtime: 6 * time.Second, Score: 3, CoveragePercent: 75, Frontier: true},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Curve() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,86 @@
+package analysis
+
+import (
+	"sort"
+	"time"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+// OrderByAdditionalCoverage orders tests for prioritization after the tests already merged into start.
+// Each step picks the test that adds the most newly covered blocks, breaking ties by shorter duration
+// and then by name. Once no test adds coverage, the rest follow in duration order.
+func OrderByAdditionalCoverage(
+	start *coverage.BlockSet,
+	names []string,
+	sets map[string]*coverage.BlockSet,
+	durations map[string]time.Duration,
+) []string {
+	current := start.Clone()
+
+	remaining := append([]string(nil), names...)
+	sort.Slice(remaining, func(i, j int) bool {
+		return fasterFirst(remaining[i], remaining[j], durations)
+	})
+
+	order := make([]string, 0, len(remaining))
+
+	for len(remaining) > 0 {
+		bestIdx := -1
+		bestGain := 0
+
+		for i, name := range remaining {
+			bs := sets[name]
+			if bs == nil {
+				continue
+			}
+
+			// remaining is in duration order, so strict > keeps the faster test on ties
+			if gain := len(current.NewBlocksFrom(bs)); gain > bestGain {
+				bestIdx = i
+				bestGain = gain
+			}
+		}
+
+		if bestIdx < 0 {
+			break
+		}
+
+		order = append(order, remaining[bestIdx])
+		current.Merge(sets[remaining[bestIdx]])
+		remaining = append(remaining[:bestIdx], remaining[bestIdx+1:]...)
+	}
+
+	return append(order, remaining...)
+}
+
+// FailedFirst puts tests that failed during analysis ahead of order, so a prioritized run surfaces
+// known breakage before anything else. Failed tests keep their given order and are not repeated.
+func FailedFirst(failed, order []string) []string {
+	result := make([]string, 0, len(failed)+len(order))
+	seen := make(map[string]bool, len(failed))
+
+	for _, name := range failed {
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+
+	for _, name := range order {
+		if !seen[name] {
+			result = append(result, name)
+		}
+	}
+
+	return result
+}
+
+// fasterFirst orders tests by duration, then by name.
+func fasterFirst(a, b string, durations map[string]time.Duration) bool {
+	if durations[a] != durations[b] {
+		return durations[a] < durations[b]
+	}
+
+	return a < b
+}
@@ -0,0 +1,94 @@
+package analysis_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/toejough/testredundancy/internal/analysis"
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+func TestOrderByAdditionalCoverage(t *testing.T) {
+	start := blockSet("f.go:1.1,2.1")
+	sets := map[string]*coverage.BlockSet{
+		"pkg:TestNothingNewSlow": blockSet("f.go:1.1,2.1"),
+		"pkg:TestNothingNewFast": blockSet("f.go:1.1,2.1"),
+		"pkg:TestOneNew":         blockSet("f.go:3.1,4.1"),
+		"pkg:TestTwoNew":         blockSet("f.go:3.1,4.1", "f.go:5.1,6.1"),
+		"pkg:TestOneNewFast":     blockSet("f.go:7.1,8.1"),
+		"pkg:TestOneNewSlow":     blockSet("f.go:9.1,10.1"),
+	}
+	durations := map[string]time.Duration{
+		"pkg:TestNothingNewSlow": 3 * time.Second,
+		"pkg:TestNothingNewFast": 1 * time.Second,
+		"pkg:TestOneNew":         1 * time.Second,
+		"pkg:TestTwoNew":         5 * time.Second,
+		"pkg:TestOneNewFast":     500 * time.Millisecond,
+		"pkg:TestOneNewSlow":     2 * time.Second,
+	}
+
+	names := []string{
+		"pkg:TestNothingNewSlow", "pkg:TestNothingNewFast", "pkg:TestOneNew",
+		"pkg:TestTwoNew", "pkg:TestOneNewFast", "pkg:TestOneNewSlow",
+	}
+
+	got := analysis.OrderByAdditionalCoverage(start, names, sets, durations)
+	want := []string{
+		"pkg:TestTwoNew",     // 2 new blocks
+		"pkg:TestOneNewFast", // 1 new block, fastest
+		"pkg:TestOneNewSlow", // 1 new block (TestOneNew now adds nothing)
+		"pkg:TestNothingNewFast",
+		"pkg:TestOneNew",
+		"pkg:TestNothingNewSlow",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderByAdditionalCoverage() = %v, want %v", got, want)
+	}
+
+	if len(start.Blocks) != 1 {
+		t.Error("start BlockSet should not be modified")
+	}
+}
+
+func TestFailedFirst(t *testing.T) {
+	tests := []struct {
+		name   string
+		failed []string
+		order  []string
+		want   []string
+	}{
+		{
+			name:  "no failures",
+			order: []string{"pkg:TestA", "pkg:TestB"},
+			want:  []string{"pkg:TestA", "pkg:TestB"},
+		},
+		{
+			name:   "failures lead",
+			failed: []string{"pkg:TestFlaky", "pkg:TestTimeout"},
+			order:  []string{"pkg:TestA", "pkg:TestB"},
+			want:   []string{"pkg:TestFlaky", "pkg:TestTimeout", "pkg:TestA", "pkg:TestB"},
+		},
+		{
+			name:   "failed test already in order is not repeated",
+			failed: []string{"pkg:TestB"},
+			order:  []string{"pkg:TestA", "pkg:TestB"},
+			want:   []string{"pkg:TestB", "pkg:TestA"},
+		},
+		{
+			name:   "only failures",
+			failed: []string{"pkg:TestA"},
+			want:   []string{"pkg:TestA"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := analysis.FailedFirst(tt.failed, tt.order)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FailedFirst() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
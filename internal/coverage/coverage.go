@@ -2,9 +2,13 @@
 package coverage
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path"
 	"sort"
 	"strconv"
 	"strings"
@@ -23,28 +27,25 @@ type Block struct {
 
 // FilterQtpl removes .qtpl template file entries from a coverage file.
 func FilterQtpl(inputFile, outputFile string) error {
-	data, err := os.ReadFile(inputFile)
-	if err != nil {
-		return fmt.Errorf("failed to read %s: %w", inputFile, err)
-	}
-
-	lines := strings.Split(string(data), "\n")
-	if len(lines) == 0 {
-		return fmt.Errorf("empty coverage file: %s", inputFile)
-	}
+	return FilterFile(inputFile, outputFile, defaultReadOptions)
+}
 
-	// Keep mode line, filter out .qtpl entries
-	filtered := []string{lines[0]} // mode line
+// FilterFile writes the entries of a coverage file that pass opts.Filter to outputFile.
+func FilterFile(inputFile, outputFile string, opts ReadOptions) error {
+	var lines []string
 
-	for _, line := range lines[1:] {
-		if line == "" || strings.Contains(line, ".qtpl:") {
-			continue
-		}
+	mode, err := readProfile(inputFile, opts, func(block Block) {
+		lines = append(lines, fmt.Sprintf("%s %d %d", block.ID(), block.Statements, block.Count))
+	})
+	if err != nil {
+		return err
+	}
 
-		filtered = append(filtered, line)
+	if mode == "" {
+		mode = ModeSet
 	}
 
-	result := strings.Join(filtered, "\n")
+	result := "mode: " + mode + "\n" + strings.Join(lines, "\n")
 
 	err = os.WriteFile(outputFile, []byte(result), 0o600)
 	if err != nil {
@@ -94,178 +95,212 @@ func GetAllFunctionsCoverage(coverageFile string) (map[string]float64, error) {
 
 // MergeBlocksFile merges duplicate coverage blocks in a coverage file (in-place).
 func MergeBlocksFile(filename string) error {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return fmt.Errorf("failed to read %s: %w", filename, err)
-	}
+	m := newProfileMerger()
 
-	lines := strings.Split(string(data), "\n")
-	if len(lines) == 0 {
-		return nil
+	if err := m.addFile(filename, ReadOptions{}); err != nil {
+		return err
 	}
 
-	// Keep the mode line
-	mode := lines[0]
-
-	// Parse all blocks
-	var blocks []Block
-	blockCounts := make(map[string]int)
+	return m.write(filename)
+}
 
-	for _, line := range lines[1:] {
-		if line == "" {
-			continue
-		}
+// MergeFiles merges multiple coverage files into a single output file.
+func MergeFiles(files []string, outputFile string) error {
+	return MergeFilesWithOptions(files, outputFile, defaultReadOptions)
+}
 
-		parts := strings.Fields(line)
-		if len(parts) != 3 {
-			continue
-		}
+// MergeFilesWithOptions is MergeFiles keeping only entries that pass opts.Filter.
+// Inputs are streamed into an index keyed by block ID, so merging is linear in the total
+// number of lines; all inputs must share one coverage mode.
+func MergeFilesWithOptions(files []string, outputFile string, opts ReadOptions) error {
+	if len(files) == 0 {
+		return fmt.Errorf("no files to merge")
+	}
 
-		blockID := parts[0]
-		numStmts, _ := strconv.Atoi(parts[1])
-		count, _ := strconv.Atoi(parts[2])
+	m := newProfileMerger()
 
-		file, startLine, startCol, endLine, endCol, err := ParseBlockID(blockID)
-		if err != nil {
-			continue
+	for _, file := range files {
+		if err := m.addFile(file, opts); err != nil {
+			return err
 		}
+	}
 
-		// Sum counts for identical blocks
-		blockCounts[blockID] += count
+	return m.write(outputFile)
+}
 
-		// Store block for deduplication
-		found := false
+// profileMerger accumulates profile blocks, combining the counts of identical blocks.
+// Note: We don't split overlapping blocks - go tool cover handles them correctly.
+// We only deduplicate identical blocks (same start/end positions): in set mode a block is
+// covered if any profile covers it, and otherwise hit counts are summed.
+type profileMerger struct {
+	mode     string
+	modeFrom string // File the mode came from, for mismatch errors
+	index    map[string]int
+	blocks   []Block
+}
 
-		for i, b := range blocks {
-			if b.File == file && b.StartLine == startLine && b.StartCol == startCol &&
-				b.EndLine == endLine && b.EndCol == endCol {
-				blocks[i].Count = blockCounts[blockID]
-				found = true
+func newProfileMerger() *profileMerger {
+	return &profileMerger{index: make(map[string]int)}
+}
 
-				break
-			}
-		}
+// addFile streams a profile's blocks into the merger, after checking its mode matches the
+// profiles already merged.
+func (m *profileMerger) addFile(filename string, opts ReadOptions) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	defer f.Close()
 
-		if !found {
-			blocks = append(blocks, Block{
-				File:       file,
-				StartLine:  startLine,
-				StartCol:   startCol,
-				EndLine:    endLine,
-				EndCol:     endCol,
-				Statements: numStmts,
-				Count:      blockCounts[blockID],
-			})
-		}
+	pr, err := NewProfileReader(f, filename, opts)
+	if err != nil {
+		return err
 	}
 
-	// Rebuild coverage file with deduplicated blocks
-	// Note: We don't split overlapping blocks - go tool cover handles them correctly.
-	// We only deduplicate identical blocks (same start/end positions) by summing counts.
-	var merged []string
-	merged = append(merged, mode)
+	switch {
+	case pr.Mode == "" || pr.Mode == m.mode:
+	case m.mode == "":
+		m.mode, m.modeFrom = pr.Mode, filename
+	default:
+		return fmt.Errorf("cannot merge %s (mode %s) with %s (mode %s): coverage modes differ",
+			filename, pr.Mode, m.modeFrom, m.mode)
+	}
 
-	// Sort for deterministic output
-	sort.Slice(blocks, func(i, j int) bool {
-		if blocks[i].File != blocks[j].File {
-			return blocks[i].File < blocks[j].File
+	for {
+		block, err := pr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
 		}
 
-		if blocks[i].StartLine != blocks[j].StartLine {
-			return blocks[i].StartLine < blocks[j].StartLine
+		if err != nil {
+			return err
 		}
 
-		return blocks[i].StartCol < blocks[j].StartCol
-	})
-
-	for _, block := range blocks {
-		blockID := fmt.Sprintf("%s:%d.%d,%d.%d",
-			block.File, block.StartLine, block.StartCol, block.EndLine, block.EndCol)
-		merged = append(merged, fmt.Sprintf("%s %d %d", blockID, block.Statements, block.Count))
+		m.add(block)
 	}
-
-	// Write merged coverage
-	return os.WriteFile(filename, []byte(strings.Join(merged, "\n")+"\n"), 0o600)
 }
 
-// MergeFiles merges multiple coverage files into a single output file.
-func MergeFiles(files []string, outputFile string) error {
-	if len(files) == 0 {
-		return fmt.Errorf("no files to merge")
+// add records a block, combining counts for identical blocks: logical OR in set mode,
+// where counts are only 0 or 1, and a sum otherwise.
+func (m *profileMerger) add(block Block) {
+	if m.mode == ModeSet {
+		block.Count = min(block.Count, 1)
 	}
 
-	var mode string
-	var allBlocks []string
+	id := block.ID()
 
-	for i, file := range files {
-		data, err := os.ReadFile(file)
-		if err != nil {
-			return fmt.Errorf("failed to read %s: %w", file, err)
+	if i, ok := m.index[id]; ok {
+		if m.mode == ModeSet {
+			m.blocks[i].Count = max(m.blocks[i].Count, block.Count)
+		} else {
+			m.blocks[i].Count += block.Count
 		}
 
-		lines := strings.Split(string(data), "\n")
-		if len(lines) == 0 {
-			continue
+		return
+	}
+
+	m.index[id] = len(m.blocks)
+	m.blocks = append(m.blocks, block)
+}
+
+// write writes the merged profile, sorted for deterministic output.
+func (m *profileMerger) write(filename string) error {
+	sort.Slice(m.blocks, func(i, j int) bool {
+		a, b := m.blocks[i], m.blocks[j]
+		if a.File != b.File {
+			return a.File < b.File
 		}
 
-		// Use mode from first file
-		if i == 0 {
-			mode = lines[0]
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
 		}
 
-		// Append blocks from this file (skip mode line and .qtpl files)
-		for _, line := range lines[1:] {
-			// Skip empty lines and lines referencing .qtpl template files
-			if line == "" || strings.Contains(line, ".qtpl:") {
-				continue
-			}
+		if a.StartCol != b.StartCol {
+			return a.StartCol < b.StartCol
+		}
 
-			allBlocks = append(allBlocks, line)
+		if a.EndLine != b.EndLine {
+			return a.EndLine < b.EndLine
 		}
-	}
 
-	// Write combined file
-	combined := mode + "\n" + strings.Join(allBlocks, "\n")
+		return a.EndCol < b.EndCol
+	})
+
+	mode := m.mode
+	if mode == "" {
+		mode = ModeSet
+	}
 
-	err := os.WriteFile(outputFile, []byte(combined), 0o600)
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
 	if err != nil {
-		return fmt.Errorf("failed to write %s: %w", outputFile, err)
+		return fmt.Errorf("failed to write %s: %w", filename, err)
 	}
 
-	// Merge overlapping blocks using existing logic
-	return MergeBlocksFile(outputFile)
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "mode: %s\n", mode)
+
+	for _, block := range m.blocks {
+		fmt.Fprintf(w, "%s %d %d\n", block.ID(), block.Statements, block.Count)
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+
+	return nil
 }
 
 // ParseBlockID parses a coverage block ID like "file.go:10.5,20.10".
+// The range follows the last colon, so file paths may contain colons (e.g., "C:\src\file.go").
 func ParseBlockID(blockID string) (file string, startLine, startCol, endLine, endCol int, err error) {
-	fileParts := strings.Split(blockID, ":")
-	if len(fileParts) != 2 {
+	idx := strings.LastIndex(blockID, ":")
+	if idx < 0 {
 		return "", 0, 0, 0, 0, fmt.Errorf("invalid block ID format: %s", blockID)
 	}
 
-	file = fileParts[0]
+	file = blockID[:idx]
 
-	rangeParts := strings.Split(fileParts[1], ",")
+	rangeParts := strings.Split(blockID[idx+1:], ",")
 	if len(rangeParts) != 2 {
 		return "", 0, 0, 0, 0, fmt.Errorf("invalid range format: %s", blockID)
 	}
 
-	startParts := strings.Split(rangeParts[0], ".")
-	if len(startParts) != 2 {
-		return "", 0, 0, 0, 0, fmt.Errorf("invalid start position: %s", blockID)
+	startLine, startCol, err = parsePosition(rangeParts[0])
+	if err != nil {
+		return "", 0, 0, 0, 0, fmt.Errorf("invalid start position: %s: %w", blockID, err)
+	}
+
+	endLine, endCol, err = parsePosition(rangeParts[1])
+	if err != nil {
+		return "", 0, 0, 0, 0, fmt.Errorf("invalid end position: %s: %w", blockID, err)
 	}
 
-	endParts := strings.Split(rangeParts[1], ".")
-	if len(endParts) != 2 {
-		return "", 0, 0, 0, 0, fmt.Errorf("invalid end position: %s", blockID)
+	return file, startLine, startCol, endLine, endCol, nil
+}
+
+// parsePosition parses a "line.col" position.
+func parsePosition(pos string) (line, col int, err error) {
+	lineStr, colStr, ok := strings.Cut(pos, ".")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected line.col, got %q", pos)
 	}
 
-	startLine, _ = strconv.Atoi(startParts[0])
-	startCol, _ = strconv.Atoi(startParts[1])
-	endLine, _ = strconv.Atoi(endParts[0])
-	endCol, _ = strconv.Atoi(endParts[1])
+	line, err = strconv.Atoi(lineStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid line %q", lineStr)
+	}
 
-	return file, startLine, startCol, endLine, endCol, nil
+	col, err = strconv.Atoi(colStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid column %q", colStr)
+	}
+
+	return line, col, nil
 }
 
 // ParseBlock parses a coverage block line like "file.go:10.5,20.10 3 1".
@@ -273,12 +308,20 @@ func ParseBlock(line string) (Block, error) {
 	// Format: file:startLine.startCol,endLine.endCol statements count
 	parts := strings.Fields(line)
 	if len(parts) != 3 {
-		return Block{}, fmt.Errorf("invalid line format")
+		return Block{}, fmt.Errorf("invalid line format: expected 3 fields, got %d", len(parts))
 	}
 
 	blockID := parts[0]
-	statements, _ := strconv.Atoi(parts[1])
-	count, _ := strconv.Atoi(parts[2])
+
+	statements, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return Block{}, fmt.Errorf("invalid statement count %q", parts[1])
+	}
+
+	count, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return Block{}, fmt.Errorf("invalid hit count %q", parts[2])
+	}
 
 	file, startLine, startCol, endLine, endCol, err := ParseBlockID(blockID)
 	if err != nil {
@@ -296,76 +339,96 @@ func ParseBlock(line string) (Block, error) {
 	}, nil
 }
 
+// ID returns the block's ID (e.g., "file.go:10.5,20.10").
+func (b Block) ID() string {
+	return fmt.Sprintf("%s:%d.%d,%d.%d", b.File, b.StartLine, b.StartCol, b.EndLine, b.EndCol)
+}
+
+// Coverage modes, as in the "mode:" line of a coverage profile (go test -covermode).
+const (
+	ModeSet    = "set"
+	ModeCount  = "count"
+	ModeAtomic = "atomic"
+)
+
 // BlockSet represents coverage data as a set of covered blocks with statement counts.
 // Key is block ID (e.g., "file.go:10.5,20.10"), value is (statements, covered).
 type BlockSet struct {
 	// Blocks maps block ID to (statements, isCovered)
 	Blocks map[string]BlockInfo
+	// Mode is the profile's coverage mode ("" is treated as ModeSet)
+	Mode string
 }
 
 // BlockInfo holds statement count and coverage status for a block.
 type BlockInfo struct {
 	Statements int
 	Covered    bool
+	Count      int // Hit count; 0 or 1 in set mode
+}
+
+// HasCounts reports whether Count holds real hit counts (count or atomic mode).
+func (bs *BlockSet) HasCounts() bool {
+	return bs.Mode == ModeCount || bs.Mode == ModeAtomic
 }
 
 // ParseFileToBlockSet parses a coverage file into an in-memory BlockSet.
 func ParseFileToBlockSet(filename string) (*BlockSet, error) {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
-	}
+	return ParseFileToBlockSetWithOptions(filename, defaultReadOptions)
+}
 
+// ParseFileToBlockSetWithOptions is ParseFileToBlockSet keeping only entries that pass opts.Filter.
+func ParseFileToBlockSetWithOptions(filename string, opts ReadOptions) (*BlockSet, error) {
 	bs := &BlockSet{Blocks: make(map[string]BlockInfo)}
-	lines := strings.Split(string(data), "\n")
-
-	for _, line := range lines[1:] { // Skip mode line
-		if line == "" || strings.Contains(line, ".qtpl:") {
-			continue
-		}
-
-		parts := strings.Fields(line)
-		if len(parts) != 3 {
-			continue
-		}
 
-		blockID := parts[0]
-		statements, _ := strconv.Atoi(parts[1])
-		count, _ := strconv.Atoi(parts[2])
+	mode, err := readProfile(filename, opts, func(block Block) {
+		blockID := block.ID()
 
-		// If block already exists, merge (keep max coverage)
+		// If block already exists, merge (keep max coverage, sum hits)
 		if existing, ok := bs.Blocks[blockID]; ok {
 			bs.Blocks[blockID] = BlockInfo{
 				Statements: existing.Statements,
-				Covered:    existing.Covered || count > 0,
+				Covered:    existing.Covered || block.Count > 0,
+				Count:      existing.Count + block.Count,
 			}
 		} else {
 			bs.Blocks[blockID] = BlockInfo{
-				Statements: statements,
-				Covered:    count > 0,
+				Statements: block.Statements,
+				Covered:    block.Count > 0,
+				Count:      block.Count,
 			}
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	bs.Mode = mode
+
 	return bs, nil
 }
 
 // Clone creates a deep copy of the BlockSet.
 func (bs *BlockSet) Clone() *BlockSet {
-	clone := &BlockSet{Blocks: make(map[string]BlockInfo, len(bs.Blocks))}
+	clone := &BlockSet{Blocks: make(map[string]BlockInfo, len(bs.Blocks)), Mode: bs.Mode}
 	for k, v := range bs.Blocks {
 		clone.Blocks[k] = v
 	}
 	return clone
 }
 
-// Merge combines another BlockSet into this one (union of coverage).
+// Merge combines another BlockSet into this one (union of coverage, sum of hit counts).
 func (bs *BlockSet) Merge(other *BlockSet) {
+	if bs.Mode == "" {
+		bs.Mode = other.Mode
+	}
+
 	for blockID, info := range other.Blocks {
 		if existing, ok := bs.Blocks[blockID]; ok {
 			bs.Blocks[blockID] = BlockInfo{
 				Statements: existing.Statements,
 				Covered:    existing.Covered || info.Covered,
+				Count:      existing.Count + info.Count,
 			}
 		} else {
 			bs.Blocks[blockID] = info
@@ -402,6 +465,40 @@ func (bs *BlockSet) CoveragePercent() float64 {
 	return float64(bs.CoveredStatements()) * 100.0 / float64(total)
 }
 
+// StatementCounts holds covered and total statement counts for a group of blocks.
+type StatementCounts struct {
+	Covered int
+	Total   int
+}
+
+// Percent returns the covered percentage, or 0 if there are no statements.
+func (c StatementCounts) Percent() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return float64(c.Covered) * 100.0 / float64(c.Total)
+}
+
+// StatementsByPackage aggregates statement counts by package import path (the directory of each block's file).
+func (bs *BlockSet) StatementsByPackage() map[string]StatementCounts {
+	result := make(map[string]StatementCounts)
+	for blockID, info := range bs.Blocks {
+		file, _, _, _, _, err := ParseBlockID(blockID)
+		if err != nil {
+			continue
+		}
+
+		pkg := path.Dir(file)
+		counts := result[pkg]
+		counts.Total += info.Statements
+		if info.Covered {
+			counts.Covered += info.Statements
+		}
+		result[pkg] = counts
+	}
+	return result
+}
+
 // NewBlocksFrom returns block IDs that are covered in other but not in bs.
 func (bs *BlockSet) NewBlocksFrom(other *BlockSet) []string {
 	var newBlocks []string
@@ -431,10 +528,16 @@ func (bs *BlockSet) CountNewStatements(other *BlockSet) int {
 	return count
 }
 
-// WriteBlockSetToFile writes a BlockSet to a coverage file.
+// WriteBlockSetToFile writes a BlockSet to a coverage file in its mode, with hit counts
+// in count and atomic modes.
 func WriteBlockSetToFile(bs *BlockSet, filename string) error {
+	mode := bs.Mode
+	if mode == "" {
+		mode = ModeSet
+	}
+
 	var lines []string
-	lines = append(lines, "mode: set")
+	lines = append(lines, "mode: "+mode)
 
 	// Sort block IDs for deterministic output
 	var blockIDs []string
@@ -448,6 +551,9 @@ func WriteBlockSetToFile(bs *BlockSet, filename string) error {
 		count := 0
 		if info.Covered {
 			count = 1
+			if bs.HasCounts() && info.Count > 0 {
+				count = info.Count
+			}
 		}
 		lines = append(lines, fmt.Sprintf("%s %d %d", blockID, info.Statements, count))
 	}
@@ -1,8 +1,10 @@
 package coverage_test
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -32,11 +34,23 @@ func TestParseBlockID(t *testing.T) {
 			wantStart: [2]int{1, 1},
 			wantEnd:   [2]int{5, 2},
 		},
+		{
+			name:      "path containing colons",
+			blockID:   `C:\src\foo\file.go:10.5,20.10`,
+			wantFile:  `C:\src\foo\file.go`,
+			wantStart: [2]int{10, 5},
+			wantEnd:   [2]int{20, 10},
+		},
 		{
 			name:    "missing colon",
 			blockID: "file.go10.5,20.10",
 			wantErr: true,
 		},
+		{
+			name:    "non-numeric line",
+			blockID: "file.go:x.5,20.10",
+			wantErr: true,
+		},
 		{
 			name:    "missing comma",
 			blockID: "file.go:10.5-20.10",
@@ -126,6 +140,11 @@ func TestParseBlock(t *testing.T) {
 			line:    "",
 			wantErr: true,
 		},
+		{
+			name:    "non-numeric count",
+			line:    "main.go:1.1,5.2 2 many",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -183,9 +202,9 @@ github.com/foo/bar.go:25.1,30.5 2 0
 		t.Error("missing mode line")
 	}
 
-	// The duplicate block should be merged (count summed: 1+1=2)
-	if !strings.Contains(result, "github.com/foo/bar.go:10.5,20.10 3 2") {
-		t.Error("duplicate blocks not merged correctly, expected count=2")
+	// The duplicate block should be merged (set mode: covered in either, count stays 1)
+	if !strings.Contains(result, "github.com/foo/bar.go:10.5,20.10 3 1") {
+		t.Error("duplicate blocks not merged correctly, expected count=1")
 	}
 
 	// The non-duplicate block should remain unchanged
@@ -236,9 +255,9 @@ github.com/foo/bar.go:35.1,40.5 4 1
 		t.Error("missing mode line")
 	}
 
-	// Block from both files should be merged (count summed: 1+1=2)
-	if !strings.Contains(result, "github.com/foo/bar.go:10.5,20.10 3 2") {
-		t.Error("overlapping blocks not merged correctly, expected count=2")
+	// Block from both files should be merged (set mode: covered in either, count stays 1)
+	if !strings.Contains(result, "github.com/foo/bar.go:10.5,20.10 3 1") {
+		t.Error("overlapping blocks not merged correctly, expected count=1")
 	}
 
 	// Block unique to file1 should remain
@@ -252,6 +271,105 @@ github.com/foo/bar.go:35.1,40.5 4 1
 	}
 }
 
+func TestMergeFilesMixedModes(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	setFile := filepath.Join(tmpDir, "set.out")
+	countFile := filepath.Join(tmpDir, "count.out")
+
+	if err := os.WriteFile(setFile, []byte("mode: set\na.go:1.1,2.1 1 1\n"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if err := os.WriteFile(countFile, []byte("mode: count\na.go:1.1,2.1 1 4\n"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	err := coverage.MergeFiles([]string{setFile, countFile}, filepath.Join(tmpDir, "merged.out"))
+	if err == nil || !strings.Contains(err.Error(), "coverage modes differ") {
+		t.Errorf("MergeFiles() error = %v, want coverage modes differ", err)
+	}
+}
+
+// writeManyProfiles writes numFiles count-mode profiles of numBlocks blocks each. The first
+// numShared blocks appear in every profile; the rest are unique to their profile.
+func writeManyProfiles(tb testing.TB, dir string, numFiles, numBlocks, numShared int) []string {
+	tb.Helper()
+
+	files := make([]string, numFiles)
+
+	for i := range files {
+		var profile strings.Builder
+		profile.WriteString("mode: count\n")
+
+		for j := range numBlocks {
+			file := "shared.go"
+			if j >= numShared {
+				file = fmt.Sprintf("f%d.go", i)
+			}
+
+			fmt.Fprintf(&profile, "github.com/foo/%s:%d.1,%d.5 1 1\n", file, j+1, j+1)
+		}
+
+		files[i] = filepath.Join(dir, fmt.Sprintf("cov%d.out", i))
+		if err := os.WriteFile(files[i], []byte(profile.String()), 0o600); err != nil {
+			tb.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	return files
+}
+
+// TestMergeFilesMany merges enough distinct blocks (about 180,000) that a merge scanning the
+// blocks merged so far for each new block would take minutes, while a keyed merge takes well
+// under a second.
+func TestMergeFilesMany(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	const numFiles, numBlocks, numShared = 200, 1000, 100
+
+	files := writeManyProfiles(t, tmpDir, numFiles, numBlocks, numShared)
+
+	outputFile := filepath.Join(tmpDir, "merged.out")
+	if err := coverage.MergeFiles(files, outputFile); err != nil {
+		t.Fatalf("MergeFiles() error: %v", err)
+	}
+
+	bs, err := coverage.ParseFileToBlockSet(outputFile)
+	if err != nil {
+		t.Fatalf("ParseFileToBlockSet() error: %v", err)
+	}
+
+	wantBlocks := numShared + numFiles*(numBlocks-numShared)
+	if len(bs.Blocks) != wantBlocks || bs.Mode != coverage.ModeCount {
+		t.Fatalf("merged %d blocks in mode %q, want %d in count", len(bs.Blocks), bs.Mode, wantBlocks)
+	}
+
+	if got := bs.Blocks["github.com/foo/shared.go:1.1,1.5"].Count; got != numFiles {
+		t.Errorf("merged shared Count = %d, want %d", got, numFiles)
+	}
+
+	if got := bs.Blocks["github.com/foo/f0.go:101.1,101.5"].Count; got != 1 {
+		t.Errorf("merged unique Count = %d, want 1", got)
+	}
+}
+
+func BenchmarkMergeFiles(b *testing.B) {
+	for _, numFiles := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("files=%d", numFiles), func(b *testing.B) {
+			tmpDir := b.TempDir()
+			files := writeManyProfiles(b, tmpDir, numFiles, 200, 50)
+			outputFile := filepath.Join(tmpDir, "merged.out")
+
+			for b.Loop() {
+				if err := coverage.MergeFiles(files, outputFile); err != nil {
+					b.Fatalf("MergeFiles() error: %v", err)
+				}
+			}
+		})
+	}
+}
+
 func TestFilterQtpl(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -298,3 +416,92 @@ github.com/foo/other.go:1.1,5.5 1 0
 		t.Error(".qtpl entries should be filtered out")
 	}
 }
+
+func TestStatementsByPackage(t *testing.T) {
+	bs := &coverage.BlockSet{Blocks: map[string]coverage.BlockInfo{
+		"github.com/foo/a/x.go:1.1,2.1": {Statements: 3, Covered: true},
+		"github.com/foo/a/y.go:1.1,2.1": {Statements: 2, Covered: false},
+		"github.com/foo/b/z.go:1.1,2.1": {Statements: 4, Covered: false},
+	}}
+
+	got := bs.StatementsByPackage()
+	want := map[string]coverage.StatementCounts{
+		"github.com/foo/a": {Covered: 3, Total: 5},
+		"github.com/foo/b": {Covered: 0, Total: 4},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StatementsByPackage() = %v, want %v", got, want)
+	}
+
+	if pct := got["github.com/foo/a"].Percent(); pct != 60 {
+		t.Errorf("Percent() = %v, want 60", pct)
+	}
+}
+
+func TestCountModeRoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	input := filepath.Join(tmpDir, "count.out")
+	content := `mode: count
+github.com/foo/bar.go:10.5,20.10 3 7
+github.com/foo/bar.go:10.5,20.10 3 2
+github.com/foo/bar.go:25.1,30.5 2 0
+`
+	if err := os.WriteFile(input, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	bs, err := coverage.ParseFileToBlockSet(input)
+	if err != nil {
+		t.Fatalf("ParseFileToBlockSet() error: %v", err)
+	}
+
+	if bs.Mode != coverage.ModeCount || !bs.HasCounts() {
+		t.Errorf("Mode = %q, want %q with counts", bs.Mode, coverage.ModeCount)
+	}
+
+	if got := bs.Blocks["github.com/foo/bar.go:10.5,20.10"].Count; got != 9 {
+		t.Errorf("duplicate block Count = %d, want 9 (summed)", got)
+	}
+
+	merged := bs.Clone()
+	merged.Merge(bs)
+
+	if got := merged.Blocks["github.com/foo/bar.go:10.5,20.10"].Count; got != 18 {
+		t.Errorf("merged Count = %d, want 18", got)
+	}
+
+	output := filepath.Join(tmpDir, "written.out")
+	if err := coverage.WriteBlockSetToFile(merged, output); err != nil {
+		t.Fatalf("WriteBlockSetToFile() error: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	want := `mode: count
+github.com/foo/bar.go:10.5,20.10 3 18
+github.com/foo/bar.go:25.1,30.5 2 0
+`
+	if string(data) != want {
+		t.Errorf("written profile =\n%s\nwant\n%s", data, want)
+	}
+
+	// Set mode collapses counts
+	merged.Mode = coverage.ModeSet
+	if err := coverage.WriteBlockSetToFile(merged, output); err != nil {
+		t.Fatalf("WriteBlockSetToFile() error: %v", err)
+	}
+
+	data, err = os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	if !strings.Contains(string(data), "mode: set\ngithub.com/foo/bar.go:10.5,20.10 3 1\n") {
+		t.Errorf("set mode profile = %q, want count collapsed to 1", data)
+	}
+}
@@ -0,0 +1,179 @@
+package coverage
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Filter decides whether coverage for a file is kept. file is the path as it appears in
+// coverage profiles (e.g., "github.com/foo/bar/file.go").
+type Filter func(file string) bool
+
+// BuiltinFilters are named exclusion filters for common non-source files.
+var BuiltinFilters = map[string]Filter{
+	// quicktemplate sources, which generated Go reports coverage against
+	"qtpl": ExcludeFiles("**/*.qtpl"),
+	// protoc output
+	"protobuf": ExcludeFiles("**/*.pb.go", "**/*.pb.gw.go"),
+	// mocks by common naming conventions
+	"mocks": ExcludeFiles("**/mocks/**", "**/mock_*.go", "**/*_mock.go", "**/*_mocks.go"),
+}
+
+// BuiltinFilterNames returns the names of the built-in filters, sorted.
+func BuiltinFilterNames() []string {
+	names := make([]string, 0, len(BuiltinFilters))
+	for name := range BuiltinFilters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// AllFilters combines filters: a file is kept only if every filter keeps it.
+func AllFilters(filters ...Filter) Filter {
+	return func(file string) bool {
+		for _, f := range filters {
+			if !f(file) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// IncludeFiles keeps only files matching at least one glob (see MatchGlob).
+func IncludeFiles(globs ...string) Filter {
+	return func(file string) bool {
+		return matchAnyGlob(globs, file)
+	}
+}
+
+// ExcludeFiles drops files matching any glob (see MatchGlob).
+func ExcludeFiles(globs ...string) Filter {
+	return func(file string) bool {
+		return !matchAnyGlob(globs, file)
+	}
+}
+
+// IncludePackages keeps only files in packages matching at least one pattern (see MatchPackage).
+func IncludePackages(patterns ...string) Filter {
+	matches := packageMatcher(patterns)
+
+	return func(file string) bool {
+		return matches(path.Dir(file))
+	}
+}
+
+// ExcludePackages drops files in packages matching any pattern (see MatchPackage).
+func ExcludePackages(patterns ...string) Filter {
+	matches := packageMatcher(patterns)
+
+	return func(file string) bool {
+		return !matches(path.Dir(file))
+	}
+}
+
+// ValidateGlob reports whether glob is a well-formed MatchGlob pattern.
+func ValidateGlob(glob string) error {
+	for _, segment := range strings.Split(glob, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidatePackage reports whether pattern is a MatchPackage pattern. Patterns match import
+// paths as they appear in coverage profiles, so relative and absolute directory patterns (e.g.,
+// "./gen/...") are rejected rather than silently matching nothing.
+func ValidatePackage(pattern string) error {
+	relative := pattern == "." || pattern == ".." || strings.HasPrefix(pattern, "./") || strings.HasPrefix(pattern, "../")
+	if relative || strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("invalid package pattern %q: must be an import path pattern (e.g., \"github.com/foo/gen/...\")", pattern)
+	}
+
+	return nil
+}
+
+// MatchGlob reports whether a slash-separated name matches glob. Each segment matches as in
+// path.Match, and a "**" segment matches zero or more whole segments (e.g., "**/mocks/**").
+// Malformed globs match nothing.
+func MatchGlob(glob, name string) bool {
+	return matchSegments(strings.Split(glob, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments matches glob segments against name segments, expanding "**".
+func matchSegments(glob, name []string) bool {
+	for len(glob) > 0 {
+		if glob[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(glob[1:], name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, err := path.Match(glob[0], name[0]); err != nil || !ok {
+			return false
+		}
+
+		glob, name = glob[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// MatchPackage reports whether an import path matches a go-style package pattern, where
+// "..." matches any string and a trailing "/..." also matches the path before it
+// (e.g., "example.com/x/..." matches "example.com/x" and "example.com/x/y").
+func MatchPackage(pattern, pkg string) bool {
+	return packageMatcher([]string{pattern})(pkg)
+}
+
+// packageMatcher compiles package patterns into a func reporting whether any matches.
+func packageMatcher(patterns []string) func(pkg string) bool {
+	bases := make(map[string]bool)
+	exprs := make([]string, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		if base, ok := strings.CutSuffix(pattern, "/..."); ok {
+			bases[base] = true
+		}
+
+		parts := strings.Split(pattern, "...")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+
+		exprs = append(exprs, strings.Join(parts, ".*"))
+	}
+
+	re := regexp.MustCompile("^(?:" + strings.Join(exprs, "|") + ")$")
+
+	return func(pkg string) bool {
+		return bases[pkg] || (len(exprs) > 0 && re.MatchString(pkg))
+	}
+}
+
+func matchAnyGlob(globs []string, file string) bool {
+	for _, glob := range globs {
+		if MatchGlob(glob, file) {
+			return true
+		}
+	}
+
+	return false
+}
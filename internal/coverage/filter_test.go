@@ -0,0 +1,121 @@
+package coverage_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		glob string
+		name string
+		want bool
+	}{
+		{"**/*.pb.go", "github.com/foo/api/api.pb.go", true},
+		{"**/*.pb.go", "api.pb.go", true},
+		{"**/*.pb.go", "github.com/foo/api/api.go", false},
+		{"**/mocks/**", "github.com/foo/mocks/db.go", true},
+		{"**/mocks/**", "github.com/foo/mocks/sub/db.go", true},
+		{"**/mocks/**", "github.com/foo/mocksdb/db.go", false},
+		{"github.com/foo/*.go", "github.com/foo/bar.go", true},
+		{"github.com/foo/*.go", "github.com/foo/sub/bar.go", false},
+		{"github.com/**/gen_*.go", "github.com/foo/x/gen_types.go", true},
+		{"[", "[", false}, // Malformed globs match nothing
+	}
+
+	for _, tt := range tests {
+		if got := coverage.MatchGlob(tt.glob, tt.name); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.glob, tt.name, got, tt.want)
+		}
+	}
+
+	if err := coverage.ValidateGlob("**/[.go"); err == nil {
+		t.Error("ValidateGlob() with unclosed bracket: expected error")
+	}
+}
+
+func TestMatchPackage(t *testing.T) {
+	tests := []struct {
+		pattern string
+		pkg     string
+		want    bool
+	}{
+		{"github.com/foo/...", "github.com/foo", true},
+		{"github.com/foo/...", "github.com/foo/bar/baz", true},
+		{"github.com/foo/...", "github.com/foobar", false},
+		{"github.com/foo", "github.com/foo", true},
+		{"github.com/foo", "github.com/foo/bar", false},
+		{".../internal/gen", "github.com/foo/internal/gen", true},
+		{"github.com/foo/...mock...", "github.com/foo/x/mocks", true},
+	}
+
+	for _, tt := range tests {
+		if got := coverage.MatchPackage(tt.pattern, tt.pkg); got != tt.want {
+			t.Errorf("MatchPackage(%q, %q) = %v, want %v", tt.pattern, tt.pkg, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePackage(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{"github.com/foo/...", false},
+		{".../internal/gen", false},
+		{".", true},
+		{"./gen/...", true},
+		{"../shared", true},
+		{"/abs/path/...", true},
+	}
+
+	for _, tt := range tests {
+		if err := coverage.ValidatePackage(tt.pattern); (err != nil) != tt.wantErr {
+			t.Errorf("ValidatePackage(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseFileToBlockSetWithOptions(t *testing.T) {
+	content := `mode: set
+github.com/foo/bar.go:10.5,20.10 3 1
+github.com/foo/bar.pb.go:1.1,5.5 2 1
+github.com/foo/mocks/db.go:1.1,5.5 2 1
+github.com/foo/gen/types.go:1.1,5.5 1 1
+github.com/foo/template.qtpl:5.1,10.5 2 1
+`
+
+	file := filepath.Join(t.TempDir(), "cov.out")
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter coverage.Filter
+		want   int // Blocks kept
+	}{
+		{"no filter", nil, 5},
+		{"qtpl builtin", coverage.BuiltinFilters["qtpl"], 4},
+		{"protobuf and mocks", coverage.AllFilters(coverage.BuiltinFilters["protobuf"], coverage.BuiltinFilters["mocks"]), 3},
+		{"exclude package", coverage.ExcludePackages("github.com/foo/gen/..."), 4},
+		{"include package only", coverage.IncludePackages("github.com/foo/gen"), 1},
+		{"include files", coverage.IncludeFiles("**/*.go"), 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs, err := coverage.ParseFileToBlockSetWithOptions(file, coverage.ReadOptions{Filter: tt.filter})
+			if err != nil {
+				t.Fatalf("ParseFileToBlockSetWithOptions() error: %v", err)
+			}
+
+			if len(bs.Blocks) != tt.want {
+				t.Errorf("kept %d blocks, want %d", len(bs.Blocks), tt.want)
+			}
+		})
+	}
+}
@@ -1,21 +1,86 @@
 package coverage
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+
+	executil "github.com/toejough/testredundancy/internal/exec"
 )
 
-// FunctionBounds represents the line range of a function in a source file.
+// DirectivePrefix starts a testredundancy directive comment on a function (e.g., "//testredundancy:threshold 100").
+const DirectivePrefix = "//testredundancy:"
+
+// FunctionBounds represents the position range of a function in a source file.
 type FunctionBounds struct {
-	Name      string // Function name (e.g., "Foo" or "(*T).Method")
-	StartLine int
-	EndLine   int
+	Name       string // Function name (e.g., "Foo", "(*T).Method", or "Foo.func1" for a function literal)
+	StartLine  int
+	StartCol   int
+	EndLine    int
+	EndCol     int
+	Directives map[string]string // Directive key -> value from the function's doc comment (nil if none)
+	Generated  bool              // In a file with a "// Code generated ... DO NOT EDIT." header
+
+	// SourceStartLine and SourceEndLine are the lines in the //line directive source (e.g., a
+	// .qtpl template) when the function is mapped to one; StartLine and EndLine stay in the
+	// generated Go file's numbering, which is what coverage profiles report. SourceLines holds
+	// the source line of each Go line from StartLine through EndLine (0 where a line maps to
+	// another file).
+	SourceStartLine int
+	SourceEndLine   int
+	SourceLines     []int
+
+	// enclosing is one more than the index, in the file's sorted bounds, of the nearest earlier
+	// bounds spanning StartLine (0 if none), so FindFunctionAt can skip bounds that end first.
+	enclosing int
+}
+
+// SourceLine returns the //line directive source line for a line of the Go file, or 0 if the
+// function is not mapped to a source or the line is not mapped into it.
+func (b FunctionBounds) SourceLine(line int) int {
+	if line < b.StartLine || line-b.StartLine >= len(b.SourceLines) {
+		return 0
+	}
+
+	return b.SourceLines[line-b.StartLine]
+}
+
+// contains reports whether the position line.col falls within the bounds.
+func (b FunctionBounds) contains(line, col int) bool {
+	if line < b.StartLine || line > b.EndLine {
+		return false
+	}
+
+	if line == b.StartLine && col < b.StartCol {
+		return false
+	}
+
+	// An EndCol of 0 means the end of the line is unknown, so the whole line counts
+	return line != b.EndLine || b.EndCol == 0 || col <= b.EndCol
+}
+
+// FunctionMapOptions controls how BuildFunctionMapWithOptions divides source into functions.
+type FunctionMapOptions struct {
+	// FuncLits records function literals as functions of their own, named like the compiler
+	// does: "Outer.func1" for the first literal in Outer, "Outer.func1.1" for a literal nested
+	// in it, and "glob..func1" for literals in package-level declarations (numbered per file).
+	FuncLits bool
+
+	// LineDirectives honors //line directives (e.g., "//line page.qtpl:12"), recording functions
+	// from generated Go under the source file they came from, as coverage profiles name them.
+	LineDirectives bool
 }
 
 // FunctionMap maps file paths to their function boundaries.
@@ -25,6 +90,11 @@ type FunctionMap map[string][]FunctionBounds
 // BuildFunctionMap parses Go source files to extract function boundaries.
 // It takes a module path (e.g., "github.com/foo/bar") and source directory.
 func BuildFunctionMap(moduleRoot string) (FunctionMap, error) {
+	return BuildFunctionMapWithOptions(moduleRoot, FunctionMapOptions{})
+}
+
+// BuildFunctionMapWithOptions is BuildFunctionMap with control over function granularity.
+func BuildFunctionMapWithOptions(moduleRoot string, opts FunctionMapOptions) (FunctionMap, error) {
 	funcMap := make(FunctionMap)
 
 	// Read go.mod to get module path
@@ -60,48 +130,18 @@ func BuildFunctionMap(moduleRoot string) (FunctionMap, error) {
 		}
 
 		// Parse the file
-		fset := token.NewFileSet()
-		file, parseErr := parser.ParseFile(fset, path, nil, 0)
+		byFile, parseErr := parseFileBounds(path, opts)
 		if parseErr != nil {
 			// Skip files that don't parse (might be build-constrained)
 			return nil
 		}
 
-		// Build the coverage file path (module path + relative path)
-		relPath, _ := filepath.Rel(moduleRoot, path)
-		coverPath := modulePath + "/" + filepath.ToSlash(relPath)
-
-		// Extract function bounds
-		var bounds []FunctionBounds
-		ast.Inspect(file, func(n ast.Node) bool {
-			switch fn := n.(type) {
-			case *ast.FuncDecl:
-				name := fn.Name.Name
-				if fn.Recv != nil && len(fn.Recv.List) > 0 {
-					// Method - include receiver type
-					recvType := exprToString(fn.Recv.List[0].Type)
-					name = "(" + recvType + ")." + name
-				}
-
-				startLine := fset.Position(fn.Pos()).Line
-				endLine := fset.Position(fn.End()).Line
-
-				bounds = append(bounds, FunctionBounds{
-					Name:      name,
-					StartLine: startLine,
-					EndLine:   endLine,
-				})
-			}
-			return true
-		})
-
-		if len(bounds) > 0 {
-			// Sort by start line for efficient lookup
-			sort.Slice(bounds, func(i, j int) bool {
-				return bounds[i].StartLine < bounds[j].StartLine
-			})
-			funcMap[coverPath] = bounds
+		// Build the coverage file path (module path + relative directory + file name)
+		dir := modulePath
+		if relDir, _ := filepath.Rel(moduleRoot, filepath.Dir(path)); relDir != "." {
+			dir += "/" + filepath.ToSlash(relDir)
 		}
+		funcMap.add(dir, byFile)
 
 		return nil
 	})
@@ -109,6 +149,265 @@ func BuildFunctionMap(moduleRoot string) (FunctionMap, error) {
 	return funcMap, err
 }
 
+// Package is a package's source files as reported by "go list -json".
+type Package struct {
+	ImportPath string
+	Dir        string
+	GoFiles    []string // Non-test .go files selected by the build context, relative to Dir
+	CgoFiles   []string // Non-test .go files that import "C", relative to Dir
+}
+
+// ListPackages runs "go list -json" on patterns (with extra build flags such as "-tags")
+// and returns the packages matched.
+func ListPackages(patterns []string, buildFlags ...string) ([]Package, error) {
+	args := append([]string{"list", "-json=ImportPath,Dir,GoFiles,CgoFiles"}, buildFlags...)
+	args = append(args, patterns...)
+
+	out, err := executil.Output(context.Background(), "go", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages %v: %w", patterns, err)
+	}
+
+	return ParsePackageList(strings.NewReader(out))
+}
+
+// ParsePackageList decodes the stream of JSON objects "go list -json" prints.
+func ParsePackageList(r io.Reader) ([]Package, error) {
+	var pkgs []Package
+
+	dec := json.NewDecoder(r)
+	for {
+		var pkg Package
+
+		err := dec.Decode(&pkg)
+		if errors.Is(err, io.EOF) {
+			return pkgs, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode go list output: %w", err)
+		}
+
+		pkgs = append(pkgs, pkg)
+	}
+}
+
+// BuildFunctionMapFromPackages parses the files of each package to extract function boundaries.
+// Unlike BuildFunctionMap, files are exactly those the build context selects (tags, GOOS/GOARCH),
+// wherever they live (nested modules, replaced or vendored packages), keyed by import path the
+// way coverage profiles name them.
+func BuildFunctionMapFromPackages(pkgs []Package, opts FunctionMapOptions) (FunctionMap, error) {
+	funcMap := make(FunctionMap)
+
+	for _, pkg := range pkgs {
+		files := append(append([]string{}, pkg.GoFiles...), pkg.CgoFiles...)
+
+		for _, name := range files {
+			byFile, err := parseFileBounds(filepath.Join(pkg.Dir, name), opts)
+			if err != nil {
+				return nil, err
+			}
+
+			funcMap.add(pkg.ImportPath, byFile)
+		}
+	}
+
+	return funcMap, nil
+}
+
+// add records bounds keyed by file base name under the package import path dir. Several Go
+// files can map to the same source through line directives, so bounds are merged.
+func (fm FunctionMap) add(dir string, byFile map[string][]FunctionBounds) {
+	for base, bounds := range byFile {
+		if len(bounds) == 0 {
+			continue
+		}
+
+		key := dir + "/" + base
+		if existing, ok := fm[key]; ok {
+			bounds = append(existing, bounds...)
+			sortBounds(bounds)
+		}
+
+		fm[key] = bounds
+	}
+}
+
+// parseFileBounds parses a Go source file and returns its function bounds sorted by start
+// position, keyed by file base name. Bounds are under the Go file's own name unless
+// opts.LineDirectives maps them to another source (e.g., "page.qtpl").
+func parseFileBounds(filename string, opts FunctionMapOptions) (map[string][]FunctionBounds, error) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+
+	c := &boundsCollector{
+		fset:           fset,
+		filename:       filename,
+		generated:      ast.IsGenerated(file),
+		lineDirectives: opts.LineDirectives,
+		byFile:         make(map[string][]FunctionBounds),
+	}
+
+	globLits := 0
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			if opts.FuncLits {
+				c.addFuncLits(decl, "glob.", &globLits)
+			}
+			continue
+		}
+
+		name := fn.Name.Name
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			// Method - include receiver type
+			recvType := exprToString(fn.Recv.List[0].Type)
+			name = "(" + recvType + ")." + name
+		}
+
+		c.add(fn, name, parseDirectives(fn.Doc))
+
+		if opts.FuncLits && fn.Body != nil {
+			lits := 0
+			c.addFuncLits(fn.Body, name, &lits)
+		}
+	}
+
+	for _, bounds := range c.byFile {
+		sortBounds(bounds)
+	}
+
+	return c.byFile, nil
+}
+
+// sortBounds sorts bounds by start position and links each to its enclosing bounds, for
+// efficient lookup.
+func sortBounds(bounds []FunctionBounds) {
+	sort.Slice(bounds, func(i, j int) bool {
+		if bounds[i].StartLine != bounds[j].StartLine {
+			return bounds[i].StartLine < bounds[j].StartLine
+		}
+		return bounds[i].StartCol < bounds[j].StartCol
+	})
+
+	// Bounds between j and j's enclosing bounds end before j starts, so following the links
+	// from i-1 visits every earlier bounds that can span i's start
+	for i := range bounds {
+		bounds[i].enclosing = 0
+
+		for j := i - 1; j >= 0; j = bounds[j].enclosing - 1 {
+			if bounds[i].StartLine <= bounds[j].EndLine {
+				bounds[i].enclosing = j + 1
+				break
+			}
+		}
+	}
+}
+
+// boundsCollector accumulates the function bounds of one parsed Go file by source file.
+type boundsCollector struct {
+	fset           *token.FileSet
+	filename       string
+	generated      bool // The Go file has a "Code generated ... DO NOT EDIT." header
+	lineDirectives bool
+	byFile         map[string][]FunctionBounds
+}
+
+// add records the position range of node under the given name. With line directives, a node
+// lying entirely in another source is recorded under that source's base name, since coverage
+// profiles name blocks by the //line file (while keeping the Go file's line numbers); that
+// source is hand-written, so not generated.
+func (c *boundsCollector) add(node ast.Node, name string, directives map[string]string) {
+	start := c.fset.PositionFor(node.Pos(), false)
+	end := c.fset.PositionFor(node.End(), false)
+
+	bounds := FunctionBounds{
+		Name:       name,
+		StartLine:  start.Line,
+		StartCol:   start.Column,
+		EndLine:    end.Line,
+		EndCol:     end.Column,
+		Directives: directives,
+		Generated:  c.generated,
+	}
+	base := path.Base(filepath.ToSlash(start.Filename))
+
+	// Like cmd/cover, which names a function's blocks by the position of its start, map the
+	// function by where it starts even if its end falls under another directive
+	if c.lineDirectives {
+		if source := c.fset.PositionFor(node.Pos(), true); source.Filename != start.Filename {
+			base = path.Base(filepath.ToSlash(source.Filename))
+			bounds.Generated = false
+			bounds.SourceStartLine = source.Line
+			bounds.SourceLines = c.sourceLines(node.Pos(), start.Line, end.Line, source.Filename)
+			bounds.SourceEndLine = slices.Max(bounds.SourceLines)
+		}
+	}
+
+	c.byFile[base] = append(c.byFile[base], bounds)
+}
+
+// sourceLines returns the line in source of each Go line from start through end of the file
+// containing pos, or 0 for lines that map to another file.
+func (c *boundsCollector) sourceLines(pos token.Pos, start, end int, source string) []int {
+	file := c.fset.File(pos)
+
+	lines := make([]int, 0, end-start+1)
+
+	for line := start; line <= end; line++ {
+		pos := c.fset.PositionFor(file.LineStart(line), true)
+		if pos.Filename != source {
+			pos.Line = 0
+		}
+
+		lines = append(lines, pos.Line)
+	}
+
+	return lines
+}
+
+// addFuncLits records the function literals in root and, recursively, those nested in them.
+// Top-level literals are named parent.funcN, counting from *count; nested literals are named
+// after their enclosing literal with a ".N" suffix.
+func (c *boundsCollector) addFuncLits(root ast.Node, parent string, count *int) {
+	ast.Inspect(root, func(n ast.Node) bool {
+		lit, ok := n.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+
+		*count++
+		name := parent + ".func" + strconv.Itoa(*count)
+		c.add(lit, name, nil)
+		c.addNestedFuncLits(lit.Body, name)
+
+		return false
+	})
+}
+
+// addNestedFuncLits records function literals inside the literal named parent.
+func (c *boundsCollector) addNestedFuncLits(body *ast.BlockStmt, parent string) {
+	count := 0
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		lit, ok := n.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+
+		count++
+		name := parent + "." + strconv.Itoa(count)
+		c.add(lit, name, nil)
+		c.addNestedFuncLits(lit.Body, name)
+
+		return false
+	})
+}
+
 // extractModulePath extracts the module path from go.mod content.
 func extractModulePath(content string) string {
 	for _, line := range strings.Split(content, "\n") {
@@ -120,6 +419,34 @@ func extractModulePath(content string) string {
 	return ""
 }
 
+// parseDirectives extracts "//testredundancy:key value" lines from a doc comment.
+func parseDirectives(doc *ast.CommentGroup) map[string]string {
+	if doc == nil {
+		return nil
+	}
+
+	var directives map[string]string
+
+	for _, c := range doc.List {
+		if !strings.HasPrefix(c.Text, DirectivePrefix) {
+			continue
+		}
+
+		key, value, _ := strings.Cut(strings.TrimPrefix(c.Text, DirectivePrefix), " ")
+		if key == "" {
+			continue
+		}
+
+		if directives == nil {
+			directives = make(map[string]string)
+		}
+
+		directives[key] = strings.TrimSpace(value)
+	}
+
+	return directives
+}
+
 // exprToString converts a receiver type expression to a string.
 func exprToString(expr ast.Expr) string {
 	switch t := expr.(type) {
@@ -140,23 +467,74 @@ func exprToString(expr ast.Expr) string {
 // FindFunction returns the function name containing the given line in a file.
 // Returns empty string if no function contains the line.
 func (fm FunctionMap) FindFunction(file string, line int) string {
+	return fm.FindFunctionAt(file, line, 0)
+}
+
+// FindFunctionAt returns the name of the innermost function containing line.col in a file,
+// so blocks inside function literals map to the literal rather than its enclosing function.
+// A col of 0 matches any function spanning the line.
+// Returns empty string if no function contains the position.
+func (fm FunctionMap) FindFunctionAt(file string, line, col int) string {
 	bounds, ok := fm[file]
 	if !ok {
 		return ""
 	}
 
-	// Binary search for the function containing this line
+	// Binary search for the last function starting at or before this line; nested bounds
+	// follow their enclosing function, so the innermost match is the first one found. Bounds
+	// skipped between a function and its enclosing one end before it starts, and so before line.
 	idx := sort.Search(len(bounds), func(i int) bool {
-		return bounds[i].EndLine >= line
+		return bounds[i].StartLine > line
 	})
 
-	if idx < len(bounds) && bounds[idx].StartLine <= line && line <= bounds[idx].EndLine {
-		return file + ":" + bounds[idx].Name
+	for i := idx - 1; i >= 0; i = bounds[i].enclosing - 1 {
+		if col == 0 {
+			if line <= bounds[i].EndLine {
+				return file + ":" + bounds[i].Name
+			}
+			continue
+		}
+
+		if bounds[i].contains(line, col) {
+			return file + ":" + bounds[i].Name
+		}
 	}
 
 	return ""
 }
 
+// GeneratedFiles returns the sorted paths of files with a "// Code generated ... DO NOT EDIT." header.
+func (fm FunctionMap) GeneratedFiles() []string {
+	var files []string
+
+	for file, bounds := range fm {
+		if len(bounds) > 0 && bounds[0].Generated {
+			files = append(files, file)
+		}
+	}
+
+	sort.Strings(files)
+
+	return files
+}
+
+// Lookup returns the bounds of a function by the name FindFunction returns (e.g., "github.com/foo/bar.go:Foo").
+func (fm FunctionMap) Lookup(funcName string) (FunctionBounds, bool) {
+	idx := strings.LastIndex(funcName, ":")
+	if idx < 0 {
+		return FunctionBounds{}, false
+	}
+
+	file, name := funcName[:idx], funcName[idx+1:]
+	for _, b := range fm[file] {
+		if b.Name == name {
+			return b, true
+		}
+	}
+
+	return FunctionBounds{}, false
+}
+
 // ComputeFunctionCoverage computes per-function coverage from a BlockSet.
 // Returns a map of function name -> coverage percentage.
 func (fm FunctionMap) ComputeFunctionCoverage(bs *BlockSet) map[string]float64 {
@@ -169,13 +547,13 @@ func (fm FunctionMap) ComputeFunctionCoverage(bs *BlockSet) map[string]float64 {
 
 	for blockID, info := range bs.Blocks {
 		// Parse block ID: "file.go:startLine.startCol,endLine.endCol"
-		file, startLine, _, _, _, err := ParseBlockID(blockID)
+		file, startLine, startCol, _, _, err := ParseBlockID(blockID)
 		if err != nil {
 			continue
 		}
 
 		// Find the function containing this block
-		funcName := fm.FindFunction(file, startLine)
+		funcName := fm.FindFunctionAt(file, startLine, startCol)
 		if funcName == "" {
 			continue
 		}
@@ -200,3 +578,144 @@ func (fm FunctionMap) ComputeFunctionCoverage(bs *BlockSet) map[string]float64 {
 
 	return result
 }
+
+// CoveredFunctions returns the sorted names of functions with at least one covered block in bs.
+func (fm FunctionMap) CoveredFunctions(bs *BlockSet) []string {
+	seen := make(map[string]bool)
+
+	for blockID, info := range bs.Blocks {
+		if !info.Covered {
+			continue
+		}
+
+		file, startLine, startCol, _, _, err := ParseBlockID(blockID)
+		if err != nil {
+			continue
+		}
+
+		if funcName := fm.FindFunctionAt(file, startLine, startCol); funcName != "" {
+			seen[funcName] = true
+		}
+	}
+
+	funcs := make([]string, 0, len(seen))
+	for fn := range seen {
+		funcs = append(funcs, fn)
+	}
+
+	sort.Strings(funcs)
+
+	return funcs
+}
+
+// FunctionStatements returns per-function covered and total statement counts for bs.
+func (fm FunctionMap) FunctionStatements(bs *BlockSet) map[string]StatementCounts {
+	result := make(map[string]StatementCounts)
+
+	for blockID, info := range bs.Blocks {
+		file, startLine, startCol, _, _, err := ParseBlockID(blockID)
+		if err != nil {
+			continue
+		}
+
+		funcName := fm.FindFunctionAt(file, startLine, startCol)
+		if funcName == "" {
+			continue
+		}
+
+		counts := result[funcName]
+		counts.Total += info.Statements
+		if info.Covered {
+			counts.Covered += info.Statements
+		}
+		result[funcName] = counts
+	}
+
+	return result
+}
+
+// BlockRange returns the position range of a block in the file its ID names (e.g., "10.5,20.10").
+// When the block's function is mapped to a //line directive source, whose lines the profile does
+// not use, the range is given in that source's lines instead (e.g., "3-4", or "3" for one line).
+func (fm FunctionMap) BlockRange(blockID string) string {
+	file, startLine, startCol, endLine, _, err := ParseBlockID(blockID)
+	if err != nil {
+		return blockID
+	}
+
+	rng := blockID[len(file)+1:]
+
+	bounds, ok := fm.Lookup(fm.FindFunctionAt(file, startLine, startCol))
+	if !ok || bounds.SourceLines == nil {
+		return rng
+	}
+
+	first, last := bounds.SourceLine(startLine), bounds.SourceLine(endLine)
+	if first == 0 || last == 0 {
+		return rng
+	}
+
+	if first == last {
+		return strconv.Itoa(first)
+	}
+
+	return fmt.Sprintf("%d-%d", first, last)
+}
+
+// BlockLocation returns the file and range (see BlockRange) of a block, e.g., "example.com/m/page.qtpl:3-4".
+func (fm FunctionMap) BlockLocation(blockID string) string {
+	file, _, _, _, _, err := ParseBlockID(blockID)
+	if err != nil {
+		return blockID
+	}
+
+	return file + ":" + fm.BlockRange(blockID)
+}
+
+// UncoveredBlocks returns, per function, the position ranges (see BlockRange) of blocks not
+// covered in bs, in source order. Functions with no uncovered blocks are omitted.
+func (fm FunctionMap) UncoveredBlocks(bs *BlockSet) map[string][]string {
+	type position struct {
+		startLine int
+		startCol  int
+		rng       string
+	}
+
+	byFunc := make(map[string][]position)
+
+	for blockID, info := range bs.Blocks {
+		if info.Covered {
+			continue
+		}
+
+		file, startLine, startCol, _, _, err := ParseBlockID(blockID)
+		if err != nil {
+			continue
+		}
+
+		funcName := fm.FindFunctionAt(file, startLine, startCol)
+		if funcName == "" {
+			continue
+		}
+
+		byFunc[funcName] = append(byFunc[funcName], position{startLine: startLine, startCol: startCol, rng: fm.BlockRange(blockID)})
+	}
+
+	result := make(map[string][]string, len(byFunc))
+
+	for fn, positions := range byFunc {
+		sort.Slice(positions, func(i, j int) bool {
+			if positions[i].startLine != positions[j].startLine {
+				return positions[i].startLine < positions[j].startLine
+			}
+
+			return positions[i].startCol < positions[j].startCol
+		})
+
+		for _, p := range positions {
+			result[fn] = append(result[fn], p.rng)
+		}
+	}
+
+	return result
+}
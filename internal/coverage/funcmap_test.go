@@ -0,0 +1,369 @@
+package coverage_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+func TestCoveredFunctions(t *testing.T) {
+	fm := coverage.FunctionMap{
+		"github.com/foo/bar.go": {
+			{Name: "Foo", StartLine: 1, EndLine: 10},
+			{Name: "(*T).Bar", StartLine: 12, EndLine: 20},
+			{Name: "Baz", StartLine: 22, EndLine: 30},
+		},
+	}
+
+	bs := &coverage.BlockSet{Blocks: map[string]coverage.BlockInfo{
+		"github.com/foo/bar.go:2.5,4.10":   {Statements: 2, Covered: true},
+		"github.com/foo/bar.go:5.5,6.10":   {Statements: 1, Covered: false},
+		"github.com/foo/bar.go:13.5,14.10": {Statements: 1, Covered: true},
+		"github.com/foo/bar.go:23.5,24.10": {Statements: 1, Covered: false},
+		"github.com/foo/other.go:1.1,2.1":  {Statements: 1, Covered: true},
+	}}
+
+	got := fm.CoveredFunctions(bs)
+	want := []string{"github.com/foo/bar.go:(*T).Bar", "github.com/foo/bar.go:Foo"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CoveredFunctions() = %v, want %v", got, want)
+	}
+}
+
+func TestBuildFunctionMapDirectives(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"go.mod": "module example.com/m\n",
+		"auth/auth.go": `package auth
+
+// Check validates a token.
+//
+//testredundancy:threshold 100
+func Check(token string) bool {
+	return token != ""
+}
+
+func helper() {}
+`,
+	}
+
+	writeFiles(t, dir, files)
+
+	fm, err := coverage.BuildFunctionMap(dir)
+	if err != nil {
+		t.Fatalf("BuildFunctionMap() error: %v", err)
+	}
+
+	check, ok := fm.Lookup("example.com/m/auth/auth.go:Check")
+	if !ok {
+		t.Fatal("Check not found in function map")
+	}
+
+	if got := check.Directives["threshold"]; got != "100" {
+		t.Errorf("threshold directive = %q, want %q", got, "100")
+	}
+
+	helper, ok := fm.Lookup("example.com/m/auth/auth.go:helper")
+	if !ok {
+		t.Fatal("helper not found in function map")
+	}
+
+	if helper.Directives != nil {
+		t.Errorf("helper directives = %v, want nil", helper.Directives)
+	}
+}
+
+func TestBuildFunctionMapFuncLits(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"srv/srv.go": `package srv
+
+var handler = func() {}
+
+func Serve() {
+	go func() {
+		defer func() {}()
+	}()
+	register(func() {})
+}
+
+func register(f func()) {}
+`,
+	})
+
+	fm, err := coverage.BuildFunctionMapWithOptions(dir, coverage.FunctionMapOptions{FuncLits: true})
+	if err != nil {
+		t.Fatalf("BuildFunctionMapWithOptions() error: %v", err)
+	}
+
+	const file = "example.com/m/srv/srv.go"
+
+	var names []string
+	for _, b := range fm[file] {
+		names = append(names, b.Name)
+	}
+
+	want := []string{"glob..func1", "Serve", "Serve.func1", "Serve.func1.1", "Serve.func2", "register"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("function names = %v, want %v", names, want)
+	}
+
+	tests := []struct {
+		line, col int
+		want      string
+	}{
+		{3, 20, file + ":glob..func1"},
+		{6, 2, file + ":Serve"},        // The go statement itself
+		{6, 12, file + ":Serve.func1"}, // Inside the goroutine body
+		{7, 17, file + ":Serve.func1.1"},
+		{9, 18, file + ":Serve.func2"},
+		{9, 2, file + ":Serve"},
+		{10, 1, file + ":Serve"}, // After every literal in Serve
+		{10, 0, file + ":Serve"},
+		{11, 0, ""}, // Between functions
+		{12, 25, file + ":register"},
+		{2, 1, ""},
+	}
+
+	for _, tt := range tests {
+		if got := fm.FindFunctionAt(file, tt.line, tt.col); got != tt.want {
+			t.Errorf("FindFunctionAt(%d, %d) = %q, want %q", tt.line, tt.col, got, tt.want)
+		}
+	}
+
+	plain, err := coverage.BuildFunctionMap(dir)
+	if err != nil {
+		t.Fatalf("BuildFunctionMap() error: %v", err)
+	}
+
+	if got := plain.FindFunctionAt(file, 7, 17); got != file+":Serve" {
+		t.Errorf("without FuncLits, FindFunctionAt(7, 17) = %q, want %q", got, file+":Serve")
+	}
+}
+
+func TestBuildFunctionMapFromPackages(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"a.go":       "package a\n\nfunc A() {}\n",
+		"a_linux.go": "package a\n\nfunc Linux() {}\n",
+		"cgo.go":     "package a\n\nimport \"C\"\n\nfunc Cgo() {}\n",
+	})
+
+	// GOOS filtering is go list's job: only the files it reports are mapped
+	listOutput := `{
+	"ImportPath": "example.com/vendored/a",
+	"Dir": "` + filepath.ToSlash(dir) + `",
+	"GoFiles": ["a.go"],
+	"CgoFiles": ["cgo.go"]
+}
+{
+	"ImportPath": "example.com/empty",
+	"Dir": "` + filepath.ToSlash(dir) + `"
+}
+`
+
+	pkgs, err := coverage.ParsePackageList(strings.NewReader(listOutput))
+	if err != nil {
+		t.Fatalf("ParsePackageList() error: %v", err)
+	}
+
+	if len(pkgs) != 2 {
+		t.Fatalf("ParsePackageList() returned %d packages, want 2", len(pkgs))
+	}
+
+	fm, err := coverage.BuildFunctionMapFromPackages(pkgs, coverage.FunctionMapOptions{})
+	if err != nil {
+		t.Fatalf("BuildFunctionMapFromPackages() error: %v", err)
+	}
+
+	var files []string
+	for file := range fm {
+		files = append(files, file)
+	}
+
+	sort.Strings(files)
+
+	want := []string{"example.com/vendored/a/a.go", "example.com/vendored/a/cgo.go"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("mapped files = %v, want %v", files, want)
+	}
+
+	if got := fm.FindFunction("example.com/vendored/a/cgo.go", 5); got != "example.com/vendored/a/cgo.go:Cgo" {
+		t.Errorf("FindFunction() = %q, want Cgo", got)
+	}
+}
+
+func TestGeneratedFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"go.mod":   "module example.com/m\n",
+		"api.go":   "package m\n\nfunc Handle() {}\n",
+		"mock.go":  "// Code generated by impgen. DO NOT EDIT.\n\npackage m\n\nfunc MockHandle() {}\n",
+		"notes.go": "package m\n\n// Code generated by hand. DO NOT EDIT. (not a header)\nfunc Notes() {}\n",
+	})
+
+	fm, err := coverage.BuildFunctionMap(dir)
+	if err != nil {
+		t.Fatalf("BuildFunctionMap() error: %v", err)
+	}
+
+	want := []string{"example.com/m/mock.go"}
+	if got := fm.GeneratedFiles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GeneratedFiles() = %v, want %v", got, want)
+	}
+
+	if b, _ := fm.Lookup("example.com/m/mock.go:MockHandle"); !b.Generated {
+		t.Error("MockHandle not marked generated")
+	}
+}
+
+func TestBuildFunctionMapLineDirectives(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"tpl/page.qtpl.go": `// Code generated by qtc from "page.qtpl". DO NOT EDIT.
+
+package tpl
+
+func helper() {}
+
+//line page.qtpl:2
+func StreamPage() {
+//line page.qtpl:3
+	helper()
+//line page.qtpl:4
+}
+
+//line page.qtpl:6
+func StreamFooter() {
+	helper()
+//line footer.qtpl:1
+}
+`,
+	})
+
+	fm, err := coverage.BuildFunctionMapWithOptions(dir, coverage.FunctionMapOptions{LineDirectives: true})
+	if err != nil {
+		t.Fatalf("BuildFunctionMapWithOptions() error: %v", err)
+	}
+
+	page, ok := fm.Lookup("example.com/m/tpl/page.qtpl:StreamPage")
+	if !ok {
+		t.Fatalf("StreamPage not mapped to the template, map: %v", fm)
+	}
+
+	// Profiles name the template but keep the Go file's line numbers
+	if page.StartLine != 8 || page.EndLine != 12 || page.Generated {
+		t.Errorf("StreamPage = lines %d-%d generated=%v, want lines 8-12 not generated",
+			page.StartLine, page.EndLine, page.Generated)
+	}
+
+	if page.SourceStartLine != 2 || page.SourceEndLine != 4 {
+		t.Errorf("StreamPage template lines = %d-%d, want 2-4", page.SourceStartLine, page.SourceEndLine)
+	}
+
+	// Block ranges are reported in template lines, since the Go file's columns don't apply
+	for blockID, want := range map[string]string{
+		"example.com/m/tpl/page.qtpl:8.19,12.2":  "2-4",
+		"example.com/m/tpl/page.qtpl:10.2,10.10": "3",
+	} {
+		if got := fm.BlockRange(blockID); got != want {
+			t.Errorf("BlockRange(%q) = %q, want %q", blockID, got, want)
+		}
+	}
+
+	if got := fm.BlockLocation("example.com/m/tpl/page.qtpl.go:6.16,6.18"); got != "example.com/m/tpl/page.qtpl.go:6.16,6.18" {
+		t.Errorf("BlockLocation() of an unmapped function = %q, want the Go range", got)
+	}
+
+	// Functions map by where they start, even when their end falls under another directive
+	footer, ok := fm.Lookup("example.com/m/tpl/page.qtpl:StreamFooter")
+	if !ok {
+		t.Fatalf("StreamFooter not mapped to the template it starts in, map: %v", fm)
+	}
+
+	if footer.SourceStartLine != 6 || footer.SourceEndLine != 8 || footer.SourceLine(18) != 0 {
+		t.Errorf("StreamFooter template lines = %d-%d, last Go line -> %d, want 6-8 and 0",
+			footer.SourceStartLine, footer.SourceEndLine, footer.SourceLine(18))
+	}
+
+	if got := fm.FindFunction("example.com/m/tpl/page.qtpl", 10); got != "example.com/m/tpl/page.qtpl:StreamPage" {
+		t.Errorf("FindFunction(page.qtpl, 10) = %q, want StreamPage", got)
+	}
+
+	if got := fm.GeneratedFiles(); !reflect.DeepEqual(got, []string{"example.com/m/tpl/page.qtpl.go"}) {
+		t.Errorf("GeneratedFiles() = %v, want only the Go file", got)
+	}
+
+	plain, err := coverage.BuildFunctionMap(dir)
+	if err != nil {
+		t.Fatalf("BuildFunctionMap() error: %v", err)
+	}
+
+	if _, ok := plain.Lookup("example.com/m/tpl/page.qtpl.go:StreamPage"); !ok {
+		t.Error("without LineDirectives, StreamPage should stay in the Go file")
+	}
+}
+
+func TestFunctionStatementsAndUncoveredBlocks(t *testing.T) {
+	fm := coverage.FunctionMap{
+		"github.com/foo/bar.go": {
+			{Name: "Foo", StartLine: 1, EndLine: 10},
+			{Name: "Bar", StartLine: 12, EndLine: 20},
+		},
+	}
+
+	bs := &coverage.BlockSet{Blocks: map[string]coverage.BlockInfo{
+		"github.com/foo/bar.go:2.5,4.10":   {Statements: 2, Covered: true},
+		"github.com/foo/bar.go:8.5,9.10":   {Statements: 1, Covered: false},
+		"github.com/foo/bar.go:5.5,6.10":   {Statements: 3, Covered: false},
+		"github.com/foo/bar.go:13.5,14.10": {Statements: 1, Covered: true},
+	}}
+
+	gotStmts := fm.FunctionStatements(bs)
+	wantStmts := map[string]coverage.StatementCounts{
+		"github.com/foo/bar.go:Foo": {Covered: 2, Total: 6},
+		"github.com/foo/bar.go:Bar": {Covered: 1, Total: 1},
+	}
+
+	if !reflect.DeepEqual(gotStmts, wantStmts) {
+		t.Errorf("FunctionStatements() = %v, want %v", gotStmts, wantStmts)
+	}
+
+	gotUncovered := fm.UncoveredBlocks(bs)
+	wantUncovered := map[string][]string{
+		"github.com/foo/bar.go:Foo": {"5.5,6.10", "8.5,9.10"},
+	}
+
+	if !reflect.DeepEqual(gotUncovered, wantUncovered) {
+		t.Errorf("UncoveredBlocks() = %v, want %v", gotUncovered, wantUncovered)
+	}
+}
+
+// writeFiles writes files (relative path -> content) under dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
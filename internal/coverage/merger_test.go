@@ -0,0 +1,53 @@
+package coverage
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestProfileMergerIndex checks that merging many profiles keeps one index entry per distinct
+// block, so each block is combined by key rather than by scanning the blocks merged so far.
+func TestProfileMergerIndex(t *testing.T) {
+	const numFiles, numBlocks, numShared = 200, 1000, 100
+
+	tests := []struct {
+		mode       string
+		wantShared int // Merged count of a block every profile covers once
+	}{
+		// Set mode records whether a block ran, so covering profiles OR to 1 rather than summing
+		{mode: ModeSet, wantShared: 1},
+		{mode: ModeCount, wantShared: numFiles},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			m := newProfileMerger()
+			m.mode = tt.mode
+
+			for i := range numFiles {
+				for j := range numBlocks {
+					file := "github.com/foo/shared.go"
+					if j >= numShared {
+						file = fmt.Sprintf("github.com/foo/f%d.go", i)
+					}
+
+					m.add(Block{File: file, StartLine: j + 1, StartCol: 1, EndLine: j + 1, EndCol: 5, Statements: 1, Count: 1})
+				}
+			}
+
+			wantBlocks := numShared + numFiles*(numBlocks-numShared)
+			if len(m.blocks) != wantBlocks || len(m.index) != wantBlocks {
+				t.Fatalf("merged %d blocks with %d index entries, want %d of each", len(m.blocks), len(m.index), wantBlocks)
+			}
+
+			i, ok := m.index["github.com/foo/shared.go:1.1,1.5"]
+			if !ok {
+				t.Fatal("shared block missing from index")
+			}
+
+			if shared := m.blocks[i]; shared.Count != tt.wantShared {
+				t.Errorf("merged shared Count = %d, want %d", shared.Count, tt.wantShared)
+			}
+		})
+	}
+}
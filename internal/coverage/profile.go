@@ -0,0 +1,166 @@
+package coverage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReadOptions controls how coverage profiles are read.
+type ReadOptions struct {
+	Filter  Filter // Keeps lines for files it accepts (nil keeps everything)
+	Lenient bool   // Skip malformed lines, and assume set mode without a valid header, instead of failing
+}
+
+// keep reports whether a profile line for file passes the filter.
+func (o ReadOptions) keep(file string) bool {
+	return o.Filter == nil || o.Filter(file)
+}
+
+// defaultReadOptions are the options of the functions that don't take ReadOptions.
+var defaultReadOptions = ReadOptions{Filter: BuiltinFilters["qtpl"]}
+
+// ParseError reports a malformed line in a coverage profile.
+type ParseError struct {
+	File string // Profile file name
+	Line int    // 1-based line number
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// ProfileReader streams the blocks of a coverage profile, validating as it goes.
+type ProfileReader struct {
+	Mode string // Coverage mode from the header (set, count, or atomic)
+
+	scanner *bufio.Scanner
+	name    string
+	line    int
+	opts    ReadOptions
+	pending *string // First line, when reading leniently without a mode header, to read as data
+}
+
+// NewProfileReader reads and validates the mode header of the profile in r. name identifies
+// the profile in errors.
+func NewProfileReader(r io.Reader, name string, opts ReadOptions) (*ProfileReader, error) {
+	pr := &ProfileReader{scanner: bufio.NewScanner(r), name: name, opts: opts}
+	pr.scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	if !pr.scanner.Scan() {
+		if err := pr.scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		if opts.Lenient {
+			return pr, nil
+		}
+
+		return nil, &ParseError{File: name, Line: 1, Msg: "empty profile, expected mode line"}
+	}
+
+	pr.line = 1
+	header := pr.scanner.Text()
+	mode, ok := strings.CutPrefix(header, "mode: ")
+
+	switch {
+	case ok && (mode == ModeSet || mode == ModeCount || mode == ModeAtomic):
+		pr.Mode = mode
+	case opts.Lenient:
+		// Without a valid header, assume set mode and read the line as data, which Next skips
+		// like any other malformed line if it doesn't parse
+		pr.Mode = ModeSet
+		pr.pending = &header
+	default:
+		return nil, &ParseError{File: name, Line: 1, Msg: fmt.Sprintf("invalid mode line %q", header)}
+	}
+
+	return pr, nil
+}
+
+// Next returns the next block that passes the filter, or io.EOF after the last one.
+// Blank lines are skipped; malformed lines are a *ParseError unless reading leniently.
+func (pr *ProfileReader) Next() (Block, error) {
+	for {
+		text, ok := pr.scan()
+		if !ok {
+			break
+		}
+
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		// Parse before filtering, so a malformed line can't slip past an include filter
+		block, err := ParseBlock(text)
+		if err != nil {
+			if pr.opts.Lenient {
+				continue
+			}
+
+			return Block{}, &ParseError{File: pr.name, Line: pr.line, Msg: err.Error()}
+		}
+
+		if !pr.opts.keep(block.File) {
+			continue
+		}
+
+		return block, nil
+	}
+
+	if err := pr.scanner.Err(); err != nil {
+		return Block{}, fmt.Errorf("failed to read %s: %w", pr.name, err)
+	}
+
+	return Block{}, io.EOF
+}
+
+// scan returns the next line to read, starting with any pending first line.
+func (pr *ProfileReader) scan() (string, bool) {
+	if pr.pending != nil {
+		text := *pr.pending
+		pr.pending = nil
+
+		return text, true
+	}
+
+	if !pr.scanner.Scan() {
+		return "", false
+	}
+
+	pr.line++
+
+	return pr.scanner.Text(), true
+}
+
+// readProfile opens a profile file and calls fn with each block that passes the filter,
+// returning the profile's mode.
+func readProfile(filename string, opts ReadOptions, fn func(Block)) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	defer f.Close()
+
+	pr, err := NewProfileReader(f, filename, opts)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		block, err := pr.Next()
+		if errors.Is(err, io.EOF) {
+			return pr.Mode, nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		fn(block)
+	}
+}
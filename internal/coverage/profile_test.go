@@ -0,0 +1,139 @@
+package coverage_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+func TestProfileReader(t *testing.T) {
+	tests := []struct {
+		name       string
+		profile    string
+		opts       coverage.ReadOptions
+		wantMode   string
+		wantBlocks []string
+		wantErr    string // Substring of the error, "" for success
+		wantLine   int    // Line of the *ParseError, if any
+	}{
+		{
+			name:       "valid",
+			profile:    "mode: count\na.go:1.1,2.1 1 5\n\nb.go:3.1,4.1 2 0\n",
+			wantMode:   "count",
+			wantBlocks: []string{"a.go:1.1,2.1", "b.go:3.1,4.1"},
+		},
+		{
+			name:       "windows path",
+			profile:    "mode: set\nC:\\src\\a.go:1.1,2.1 1 1\n",
+			wantMode:   "set",
+			wantBlocks: []string{"C:\\src\\a.go:1.1,2.1"},
+		},
+		{
+			name:     "missing mode header",
+			profile:  "a.go:1.1,2.1 1 1\n",
+			wantErr:  "invalid mode line",
+			wantLine: 1,
+		},
+		{
+			name:     "unknown mode",
+			profile:  "mode: sometimes\n",
+			wantErr:  "invalid mode line",
+			wantLine: 1,
+		},
+		{
+			name:     "empty profile",
+			profile:  "",
+			wantErr:  "empty profile",
+			wantLine: 1,
+		},
+		{
+			name:     "malformed line",
+			profile:  "mode: set\na.go:1.1,2.1 1 1\na.go:3.1,4.1 1\n",
+			wantErr:  "expected 3 fields",
+			wantLine: 3,
+		},
+		{
+			name:     "bad count",
+			profile:  "mode: set\na.go:1.1,2.1 1 x\n",
+			wantErr:  "invalid hit count",
+			wantLine: 2,
+		},
+		{
+			name:       "lenient skips malformed lines",
+			profile:    "garbage\na.go:1.1,2.1 1 1\na.go:3.1 1 1\nb.go:3.1,4.1 1 0\n",
+			opts:       coverage.ReadOptions{Lenient: true},
+			wantMode:   "set",
+			wantBlocks: []string{"a.go:1.1,2.1", "b.go:3.1,4.1"},
+		},
+		{
+			name:       "lenient reads a headerless first line as data",
+			profile:    "a.go:1.1,2.1 1 1\nb.go:3.1,4.1 1 0\n",
+			opts:       coverage.ReadOptions{Lenient: true},
+			wantMode:   "set",
+			wantBlocks: []string{"a.go:1.1,2.1", "b.go:3.1,4.1"},
+		},
+		{
+			name:       "filter drops excluded files",
+			profile:    "mode: set\npage.qtpl:1.1,2.1 1 1\na.go:1.1,2.1 1 1\n",
+			opts:       coverage.ReadOptions{Filter: coverage.BuiltinFilters["qtpl"]},
+			wantMode:   "set",
+			wantBlocks: []string{"a.go:1.1,2.1"},
+		},
+		{
+			name:     "malformed line is an error under an include filter",
+			profile:  "mode: set\na.go:1.1,2.1 1 1\ngarbage\n",
+			opts:     coverage.ReadOptions{Filter: coverage.IncludeFiles("**/*.go")},
+			wantErr:  "expected 3 fields",
+			wantLine: 3,
+		},
+		{
+			name:     "malformed line in an excluded file is an error",
+			profile:  "mode: set\npage.qtpl:1.1 bad line\n",
+			opts:     coverage.ReadOptions{Filter: coverage.BuiltinFilters["qtpl"]},
+			wantErr:  "invalid statement count",
+			wantLine: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var blocks []string
+
+			pr, err := coverage.NewProfileReader(strings.NewReader(tt.profile), "cov.out", tt.opts)
+			for err == nil {
+				var block coverage.Block
+				if block, err = pr.Next(); err == nil {
+					blocks = append(blocks, block.ID())
+				}
+			}
+
+			if tt.wantErr == "" {
+				if !errors.Is(err, io.EOF) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if pr.Mode != tt.wantMode {
+					t.Errorf("Mode = %q, want %q", pr.Mode, tt.wantMode)
+				}
+
+				if strings.Join(blocks, " ") != strings.Join(tt.wantBlocks, " ") {
+					t.Errorf("blocks = %v, want %v", blocks, tt.wantBlocks)
+				}
+
+				return
+			}
+
+			var parseErr *coverage.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("error = %v, want *ParseError", err)
+			}
+
+			if parseErr.File != "cov.out" || parseErr.Line != tt.wantLine || !strings.Contains(parseErr.Msg, tt.wantErr) {
+				t.Errorf("error = %v, want cov.out:%d containing %q", err, tt.wantLine, tt.wantErr)
+			}
+		})
+	}
+}
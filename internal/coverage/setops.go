@@ -0,0 +1,277 @@
+package coverage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Set operations treat a BlockSet as the set of its covered blocks; uncovered blocks are
+// ignored, and results contain only covered blocks.
+
+// Intersect returns the blocks covered in both bs and other, with the smaller hit count.
+func (bs *BlockSet) Intersect(other *BlockSet) *BlockSet {
+	result := &BlockSet{Blocks: make(map[string]BlockInfo), Mode: bs.Mode}
+
+	for blockID, info := range bs.Blocks {
+		if o, ok := other.Blocks[blockID]; ok && info.Covered && o.Covered {
+			info.Count = min(info.Count, o.Count)
+			result.Blocks[blockID] = info
+		}
+	}
+
+	return result
+}
+
+// Difference returns the blocks covered in bs but not in other.
+func (bs *BlockSet) Difference(other *BlockSet) *BlockSet {
+	result := &BlockSet{Blocks: make(map[string]BlockInfo), Mode: bs.Mode}
+
+	for blockID, info := range bs.Blocks {
+		if info.Covered && !other.covers(blockID) {
+			result.Blocks[blockID] = info
+		}
+	}
+
+	return result
+}
+
+// SymmetricDifference returns the blocks covered in exactly one of bs and other.
+func (bs *BlockSet) SymmetricDifference(other *BlockSet) *BlockSet {
+	result := bs.Difference(other)
+
+	for blockID, info := range other.Blocks {
+		if info.Covered && !bs.covers(blockID) {
+			result.Blocks[blockID] = info
+		}
+	}
+
+	return result
+}
+
+// IsSubsetOf reports whether every block covered in bs is also covered in other.
+func (bs *BlockSet) IsSubsetOf(other *BlockSet) bool {
+	for blockID, info := range bs.Blocks {
+		if info.Covered && !other.covers(blockID) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Equal reports whether bs and other cover exactly the same blocks.
+func (bs *BlockSet) Equal(other *BlockSet) bool {
+	return bs.CoveredBlockCount() == other.CoveredBlockCount() && bs.IsSubsetOf(other)
+}
+
+// Jaccard returns the Jaccard similarity of the blocks covered by bs and other.
+// Returns 0 if neither covers anything.
+func (bs *BlockSet) Jaccard(other *BlockSet) float64 {
+	intersection := 0
+
+	for blockID, info := range bs.Blocks {
+		if info.Covered && other.covers(blockID) {
+			intersection++
+		}
+	}
+
+	union := bs.CoveredBlockCount() + other.CoveredBlockCount() - intersection
+	if union == 0 {
+		return 0
+	}
+
+	return float64(intersection) / float64(union)
+}
+
+// CoveredBlockCount returns the number of covered blocks.
+func (bs *BlockSet) CoveredBlockCount() int {
+	count := 0
+
+	for _, info := range bs.Blocks {
+		if info.Covered {
+			count++
+		}
+	}
+
+	return count
+}
+
+// covers reports whether blockID is covered in bs.
+func (bs *BlockSet) covers(blockID string) bool {
+	info, ok := bs.Blocks[blockID]
+	return ok && info.Covered
+}
+
+// StatementsByFile aggregates statement counts by file path.
+func (bs *BlockSet) StatementsByFile() map[string]StatementCounts {
+	result := make(map[string]StatementCounts)
+
+	for blockID, info := range bs.Blocks {
+		file, _, _, _, _, err := ParseBlockID(blockID)
+		if err != nil {
+			continue
+		}
+
+		counts := result[file]
+		counts.Total += info.Statements
+		if info.Covered {
+			counts.Covered += info.Statements
+		}
+		result[file] = counts
+	}
+
+	return result
+}
+
+// blockSetMagic starts the binary encoding of a BlockSet, followed by a version byte.
+const blockSetMagic = "TRBS"
+
+const blockSetVersion = 1
+
+// MarshalBinary encodes the BlockSet compactly: file paths are stored once in a table, and
+// positions and counts as varints. Blocks are written in ID order, so equal sets encode equally.
+func (bs *BlockSet) MarshalBinary() ([]byte, error) {
+	ids := make([]string, 0, len(bs.Blocks))
+	for blockID := range bs.Blocks {
+		ids = append(ids, blockID)
+	}
+
+	sort.Strings(ids)
+
+	blocks := make([]Block, len(ids))
+	fileIndex := make(map[string]int)
+	var files []string
+
+	for i, blockID := range ids {
+		file, startLine, startCol, endLine, endCol, err := ParseBlockID(blockID)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := fileIndex[file]; !ok {
+			fileIndex[file] = len(files)
+			files = append(files, file)
+		}
+
+		blocks[i] = Block{File: file, StartLine: startLine, StartCol: startCol, EndLine: endLine, EndCol: endCol}
+	}
+
+	buf := append([]byte(blockSetMagic), blockSetVersion)
+	buf = appendString(buf, bs.Mode)
+
+	buf = binary.AppendUvarint(buf, uint64(len(files)))
+	for _, file := range files {
+		buf = appendString(buf, file)
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(blocks)))
+	for i, b := range blocks {
+		info := bs.Blocks[ids[i]]
+
+		for _, v := range []int{fileIndex[b.File], b.StartLine, b.StartCol, b.EndLine, b.EndCol, info.Statements, info.Count} {
+			buf = binary.AppendUvarint(buf, uint64(max(v, 0)))
+		}
+
+		covered := byte(0)
+		if info.Covered {
+			covered = 1
+		}
+		buf = append(buf, covered)
+	}
+
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a BlockSet encoded by MarshalBinary, replacing its contents.
+func (bs *BlockSet) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+
+	header := make([]byte, len(blockSetMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:len(blockSetMagic)]) != blockSetMagic {
+		return errors.New("not an encoded BlockSet")
+	}
+
+	if header[len(blockSetMagic)] != blockSetVersion {
+		return fmt.Errorf("unsupported BlockSet encoding version %d", header[len(blockSetMagic)])
+	}
+
+	mode, err := readString(r)
+	if err != nil {
+		return err
+	}
+
+	numFiles, err := binary.ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("corrupt BlockSet encoding: %w", err)
+	}
+
+	files := make([]string, 0, min(numFiles, uint64(r.Len())))
+	for range numFiles {
+		file, err := readString(r)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, file)
+	}
+
+	numBlocks, err := binary.ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("corrupt BlockSet encoding: %w", err)
+	}
+
+	blocks := make(map[string]BlockInfo, min(numBlocks, uint64(r.Len())))
+
+	for range numBlocks {
+		var v [7]int
+
+		for i := range v {
+			u, err := binary.ReadUvarint(r)
+			if err != nil {
+				return fmt.Errorf("corrupt BlockSet encoding: %w", err)
+			}
+
+			v[i] = int(u)
+		}
+
+		covered, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("corrupt BlockSet encoding: %w", err)
+		}
+
+		if v[0] >= len(files) {
+			return fmt.Errorf("corrupt BlockSet encoding: file index %d out of range", v[0])
+		}
+
+		b := Block{File: files[v[0]], StartLine: v[1], StartCol: v[2], EndLine: v[3], EndCol: v[4]}
+		blocks[b.ID()] = BlockInfo{Statements: v[5], Count: v[6], Covered: covered == 1}
+	}
+
+	bs.Blocks = blocks
+	bs.Mode = mode
+
+	return nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return "", errors.New("corrupt BlockSet encoding: bad string")
+	}
+
+	s := make([]byte, n)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return "", fmt.Errorf("corrupt BlockSet encoding: %w", err)
+	}
+
+	return string(s), nil
+}
@@ -0,0 +1,134 @@
+package coverage_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+// coveredSet builds a BlockSet covering the given block IDs plus an uncovered block.
+func coveredSet(covered ...string) *coverage.BlockSet {
+	bs := &coverage.BlockSet{Blocks: map[string]coverage.BlockInfo{
+		"a.go:99.1,99.2": {Statements: 1},
+	}}
+
+	for _, blockID := range covered {
+		bs.Blocks[blockID] = coverage.BlockInfo{Statements: 1, Covered: true, Count: 1}
+	}
+
+	return bs
+}
+
+// coveredIDs returns the sorted covered block IDs of bs.
+func coveredIDs(bs *coverage.BlockSet) []string {
+	ids := []string{}
+
+	for blockID, info := range bs.Blocks {
+		if info.Covered {
+			ids = append(ids, blockID)
+		}
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+func TestBlockSetAlgebra(t *testing.T) {
+	const b1, b2, b3 = "a.go:1.1,2.1", "a.go:3.1,4.1", "b.go:1.1,2.1"
+
+	a := coveredSet(b1, b2)
+	b := coveredSet(b2, b3)
+
+	tests := []struct {
+		name string
+		got  *coverage.BlockSet
+		want []string
+	}{
+		{"intersect", a.Intersect(b), []string{b2}},
+		{"difference", a.Difference(b), []string{b1}},
+		{"symmetric difference", a.SymmetricDifference(b), []string{b1, b3}},
+	}
+
+	for _, tt := range tests {
+		if got := coveredIDs(tt.got); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+
+		if len(tt.got.Blocks) != len(tt.want) {
+			t.Errorf("%s kept uncovered blocks: %v", tt.name, tt.got.Blocks)
+		}
+	}
+
+	if !coveredSet(b2).IsSubsetOf(a) || a.IsSubsetOf(b) {
+		t.Error("IsSubsetOf() wrong")
+	}
+
+	if !a.Equal(coveredSet(b2, b1)) || a.Equal(b) || a.Equal(coveredSet(b1)) {
+		t.Error("Equal() wrong")
+	}
+
+	if got := a.Jaccard(b); got != 1.0/3.0 {
+		t.Errorf("Jaccard() = %v, want 1/3", got)
+	}
+
+	if got := coveredSet().Jaccard(coveredSet()); got != 0 {
+		t.Errorf("Jaccard() of empty sets = %v, want 0", got)
+	}
+}
+
+func TestStatementsByFile(t *testing.T) {
+	bs := &coverage.BlockSet{Blocks: map[string]coverage.BlockInfo{
+		"github.com/foo/a/x.go:1.1,2.1": {Statements: 3, Covered: true},
+		"github.com/foo/a/x.go:3.1,4.1": {Statements: 2, Covered: false},
+		"github.com/foo/b/z.go:1.1,2.1": {Statements: 4, Covered: true},
+	}}
+
+	want := map[string]coverage.StatementCounts{
+		"github.com/foo/a/x.go": {Covered: 3, Total: 5},
+		"github.com/foo/b/z.go": {Covered: 4, Total: 4},
+	}
+
+	if got := bs.StatementsByFile(); !reflect.DeepEqual(got, want) {
+		t.Errorf("StatementsByFile() = %v, want %v", got, want)
+	}
+}
+
+func TestBlockSetBinaryRoundTrip(t *testing.T) {
+	bs := &coverage.BlockSet{Mode: coverage.ModeCount, Blocks: map[string]coverage.BlockInfo{
+		"github.com/foo/a/x.go:1.1,2.10": {Statements: 3, Covered: true, Count: 42},
+		"github.com/foo/a/x.go:3.1,4.1":  {Statements: 2},
+		`C:\src\foo\y.go:100.5,200.2`:    {Statements: 7, Covered: true, Count: 1},
+	}}
+
+	data, err := bs.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error: %v", err)
+	}
+
+	var decoded coverage.BlockSet
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error: %v", err)
+	}
+
+	if !reflect.DeepEqual(&decoded, bs) {
+		t.Errorf("round trip = %+v, want %+v", decoded, *bs)
+	}
+
+	again, err := decoded.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error: %v", err)
+	}
+
+	if string(again) != string(data) {
+		t.Error("encoding is not deterministic")
+	}
+
+	for _, corrupt := range [][]byte{nil, []byte("nope"), data[:len(data)-3]} {
+		if err := new(coverage.BlockSet).UnmarshalBinary(corrupt); err == nil {
+			t.Errorf("UnmarshalBinary(%q) expected error", corrupt)
+		}
+	}
+}
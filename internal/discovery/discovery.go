@@ -25,9 +25,16 @@ func (t TestInfo) QualifiedName() string {
 }
 
 // ListTests lists all test functions with their packages for the given package pattern.
-func ListTests(pkgPattern string) ([]TestInfo, error) {
-	// First, expand the package pattern to get actual packages
-	listOut, err := executil.Output(context.Background(), "go", "list", pkgPattern)
+// Extra build flags (e.g., "-tags", "integration") are passed to go list and go test.
+func ListTests(pkgPattern string, buildFlags ...string) ([]TestInfo, error) {
+	return ListTestsIn([]string{pkgPattern}, buildFlags...)
+}
+
+// ListTestsIn lists all test functions with their packages for the given package patterns.
+func ListTestsIn(pkgPatterns []string, buildFlags ...string) ([]TestInfo, error) {
+	// First, expand the package patterns to get actual packages
+	listArgs := append(append([]string{"list"}, buildFlags...), pkgPatterns...)
+	listOut, err := executil.Output(context.Background(), "go", listArgs...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list packages: %w", err)
 	}
@@ -40,7 +47,8 @@ func ListTests(pkgPattern string) ([]TestInfo, error) {
 			continue
 		}
 
-		out, err := executil.Output(context.Background(), "go", "test", "-list", ".", pkg)
+		testArgs := append(append([]string{"test"}, buildFlags...), "-list", ".", pkg)
+		out, err := executil.Output(context.Background(), "go", testArgs...)
 		if err != nil {
 			// Package may have no tests, skip it
 			continue
@@ -0,0 +1,40 @@
+package discovery
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// testEvent is the subset of a "go test -json" event needed to time a test.
+type testEvent struct {
+	Action  string
+	Test    string
+	Elapsed float64
+}
+
+// TestElapsed returns the elapsed time "go test -json" reported for the named top-level test,
+// excluding build and process startup. It reports false when the output has no result for the test.
+func TestElapsed(r io.Reader, name string) (time.Duration, bool) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var event testEvent
+		if json.Unmarshal(scanner.Bytes(), &event) != nil {
+			continue
+		}
+
+		if event.Test != name {
+			continue
+		}
+
+		switch event.Action {
+		case "pass", "fail", "skip":
+			return time.Duration(event.Elapsed * float64(time.Second)), true
+		}
+	}
+
+	return 0, false
+}
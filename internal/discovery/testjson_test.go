@@ -0,0 +1,42 @@
+package discovery_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toejough/testredundancy/internal/discovery"
+)
+
+func TestTestElapsed(t *testing.T) {
+	output := strings.Join([]string{
+		`{"Action":"start","Package":"example.com/pkg"}`,
+		`{"Action":"run","Package":"example.com/pkg","Test":"TestFoo"}`,
+		`{"Action":"output","Package":"example.com/pkg","Test":"TestFoo","Output":"=== RUN   TestFoo\n"}`,
+		`{"Action":"pass","Package":"example.com/pkg","Test":"TestFoo/sub","Elapsed":0.1}`,
+		`{"Action":"pass","Package":"example.com/pkg","Test":"TestFoo","Elapsed":0.25}`,
+		`not json`,
+		`{"Action":"fail","Package":"example.com/pkg","Test":"TestBar","Elapsed":1.5}`,
+		`{"Action":"pass","Package":"example.com/pkg","Elapsed":3.2}`,
+	}, "\n")
+
+	tests := []struct {
+		name   string
+		test   string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "passing test ignores subtests", test: "TestFoo", want: 250 * time.Millisecond, wantOK: true},
+		{name: "failing test", test: "TestBar", want: 1500 * time.Millisecond, wantOK: true},
+		{name: "missing test", test: "TestMissing", want: 0, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := discovery.TestElapsed(strings.NewReader(output), tt.test)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("TestElapsed(%q) = %v, %v, want %v, %v", tt.test, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
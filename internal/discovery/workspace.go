@@ -0,0 +1,108 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	executil "github.com/toejough/testredundancy/internal/exec"
+)
+
+// Module is a workspace module as reported by "go list -m -json".
+type Module struct {
+	Path string
+	Dir  string
+}
+
+// WorkspaceModules returns the modules of the active go.work workspace, or nil when
+// workspace mode is off.
+func WorkspaceModules() ([]Module, error) {
+	gowork, err := executil.Output(context.Background(), "go", "env", "GOWORK")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for go.work: %w", err)
+	}
+
+	gowork = strings.TrimSpace(gowork)
+	if gowork == "" || gowork == "off" {
+		return nil, nil
+	}
+
+	out, err := executil.Output(context.Background(), "go", "list", "-m", "-json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace modules: %w", err)
+	}
+
+	return ParseModuleList(strings.NewReader(out))
+}
+
+// ParseModuleList decodes the stream of JSON objects "go list -m -json" prints.
+func ParseModuleList(r io.Reader) ([]Module, error) {
+	var modules []Module
+
+	dec := json.NewDecoder(r)
+	for {
+		var mod Module
+
+		err := dec.Decode(&mod)
+		if errors.Is(err, io.EOF) {
+			return modules, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode go list output: %w", err)
+		}
+
+		modules = append(modules, mod)
+	}
+}
+
+// ExpandWorkspacePattern rewrites a relative recursive pattern (e.g., "./...") into one
+// "<module>/..." pattern per workspace module under its directory, since relative patterns
+// don't cross module boundaries. When the directory is inside a module rather than at a module
+// root, the original pattern is kept first for that module's own packages. Other patterns, and
+// patterns matching no module, are returned unchanged. dir is the absolute directory relative
+// patterns are resolved against.
+func ExpandWorkspacePattern(pattern, dir string, modules []Module) []string {
+	if !strings.HasPrefix(pattern, ".") || !strings.HasSuffix(pattern, "/...") {
+		return []string{pattern}
+	}
+
+	base := filepath.Join(dir, filepath.FromSlash(strings.TrimSuffix(pattern, "/...")))
+
+	var expanded []string
+
+	insideModule := false
+
+	for _, mod := range modules {
+		if within(base, mod.Dir) {
+			expanded = append(expanded, mod.Path+"/...")
+
+			continue
+		}
+
+		if within(mod.Dir, base) {
+			insideModule = true
+		}
+	}
+
+	if len(expanded) == 0 {
+		return []string{pattern}
+	}
+
+	if insideModule {
+		expanded = append([]string{pattern}, expanded...)
+	}
+
+	return expanded
+}
+
+// within reports whether target is dir or a directory below it.
+func within(dir, target string) bool {
+	rel, err := filepath.Rel(dir, target)
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
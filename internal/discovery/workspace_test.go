@@ -0,0 +1,107 @@
+package discovery_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/toejough/testredundancy/internal/discovery"
+)
+
+func TestParseModuleList(t *testing.T) {
+	out := `{
+	"Path": "example.com/api",
+	"Main": true,
+	"Dir": "/ws/api",
+	"GoMod": "/ws/api/go.mod"
+}
+{
+	"Path": "example.com/web",
+	"Main": true,
+	"Dir": "/ws/web"
+}
+`
+
+	got, err := discovery.ParseModuleList(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("ParseModuleList() error: %v", err)
+	}
+
+	want := []discovery.Module{
+		{Path: "example.com/api", Dir: "/ws/api"},
+		{Path: "example.com/web", Dir: "/ws/web"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseModuleList() = %v, want %v", got, want)
+	}
+
+	if _, err := discovery.ParseModuleList(strings.NewReader("{")); err == nil {
+		t.Error("ParseModuleList() with truncated input: expected error")
+	}
+}
+
+func TestExpandWorkspacePattern(t *testing.T) {
+	ws := filepath.FromSlash("/ws")
+	modules := []discovery.Module{
+		{Path: "example.com/api", Dir: filepath.Join(ws, "api")},
+		{Path: "example.com/web", Dir: filepath.Join(ws, "web")},
+		{Path: "example.com/tools", Dir: filepath.Join(ws, "tools", "gen")},
+		{Path: "example.com/apix", Dir: filepath.Join(ws, "apix")},
+		{Path: "example.com/api/gen", Dir: filepath.Join(ws, "api", "internal", "gen")},
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{
+			name:    "all modules",
+			pattern: "./...",
+			want: []string{
+				"example.com/api/...", "example.com/web/...", "example.com/tools/...", "example.com/apix/...",
+				"example.com/api/gen/...",
+			},
+		},
+		{
+			name:    "modules under a directory",
+			pattern: "./tools/...",
+			want:    []string{"example.com/tools/..."},
+		},
+		{
+			name:    "single module and its nested module, not its prefix siblings",
+			pattern: "./api/...",
+			want:    []string{"example.com/api/...", "example.com/api/gen/..."},
+		},
+		{
+			name:    "import path pattern unchanged",
+			pattern: "example.com/api/...",
+			want:    []string{"example.com/api/..."},
+		},
+		{
+			name:    "non-recursive pattern unchanged",
+			pattern: "./api",
+			want:    []string{"./api"},
+		},
+		{
+			name:    "directory inside a module keeps its own packages",
+			pattern: "./api/internal/...",
+			want:    []string{"./api/internal/...", "example.com/api/gen/..."},
+		},
+		{
+			name:    "no module under directory",
+			pattern: "./web/internal/...",
+			want:    []string{"./web/internal/..."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := discovery.ExpandWorkspacePattern(tt.pattern, ws, modules)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandWorkspacePattern(%q) = %v, want %v", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
@@ -24,8 +24,17 @@ func Output(ctx context.Context, command string, args ...string) (string, error)
 
 // RunQuietCoverage runs a command and filters out expected coverage warnings.
 func RunQuietCoverage(command string, arg ...string) error {
+	_, err := OutputQuietCoverage(command, arg...)
+
+	return err
+}
+
+// OutputQuietCoverage runs a command, captures stdout, and filters out expected coverage warnings.
+func OutputQuietCoverage(command string, arg ...string) (string, error) {
+	stdoutBuf := &bytes.Buffer{}
 	cmd := exec.Command(command, arg...)
 	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdoutBuf
 
 	// Capture stderr to filter out coverage warnings
 	var stderrBuf bytes.Buffer
@@ -49,7 +58,7 @@ func RunQuietCoverage(command string, arg ...string) error {
 		fmt.Fprintln(os.Stderr, line)
 	}
 
-	return err
+	return stdoutBuf.String(), err
 }
 
 // Sanitize makes a string safe for use in filenames.
@@ -0,0 +1,66 @@
+package testredundancy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// jsonReport is the machine-readable report written to Config.JSONReport.
+type jsonReport struct {
+	Fingerprint string                `json:"fingerprint"`
+	Kept        []jsonTest            `json:"kept"`
+	Redundant   []jsonTest            `json:"redundant"`
+	Undertested []undertestedFunction `json:"undertested"`
+	Uncovered   []string              `json:"uncovered"` // Functions with zero coverage
+}
+
+// jsonTest is a test in the JSON report.
+type jsonTest struct {
+	Package  string  `json:"package"`
+	Name     string  `json:"name"`
+	Baseline bool    `json:"baseline"`
+	Gain     float64 `json:"gain,omitempty"`
+	Reason   string  `json:"reason,omitempty"`
+}
+
+// undertestedFunction is a function below its threshold even with all tests combined.
+type undertestedFunction struct {
+	Function   string   `json:"function"`
+	Statements int      `json:"statements"`
+	Coverage   float64  `json:"coverage"` // Best achieved coverage, with all tests
+	Threshold  float64  `json:"threshold"`
+	Uncovered  []string `json:"uncoveredBlocks"` // Position ranges of uncovered blocks (e.g., "10.5,20.10", or template lines "3-4")
+}
+
+// toJSONTests converts test results for the JSON report.
+func toJSONTests(results []testResult) []jsonTest {
+	tests := make([]jsonTest, 0, len(results))
+
+	for _, r := range results {
+		tests = append(tests, jsonTest{
+			Package:  r.pkg,
+			Name:     r.name,
+			Baseline: r.isBaseline,
+			Gain:     r.gain,
+			Reason:   r.reason,
+		})
+	}
+
+	return tests
+}
+
+// writeJSONReport writes the report as indented JSON.
+func writeJSONReport(filename string, report jsonReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	err = os.WriteFile(filename, append(data, '\n'), 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package testredundancy
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+	"github.com/toejough/testredundancy/internal/discovery"
+)
+
+// locality decides which tests count toward a package's own coverage: tests in the same
+// package, plus tests in allowlisted packages, which count toward every package.
+type locality struct {
+	allowlist map[string]bool
+}
+
+// resolveAllowlist expands allowlist package patterns to import paths with go list. Patterns
+// don't depend on coverage, so Find resolves them before running any test.
+func resolveAllowlist(patterns []string, buildFlags ...string) (map[string]bool, error) {
+	allowlist := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		packages, err := listPackages(pattern, buildFlags...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve allowlist package %s: %w", pattern, err)
+		}
+
+		for pkg := range packages {
+			allowlist[pkg] = true
+		}
+	}
+
+	return allowlist, nil
+}
+
+// counts reports whether a test in testPkg counts toward coverage of code in funcPkg.
+func (l *locality) counts(testPkg, funcPkg string) bool {
+	return testPkg == funcPkg || l.allowlist[testPkg]
+}
+
+// localCoverage merges the coverage of the tests that count toward pkg.
+func (s *selector) localCoverage(pkg string, tests []discovery.TestInfo) *coverage.BlockSet {
+	merged := &coverage.BlockSet{Blocks: make(map[string]coverage.BlockInfo)}
+
+	for _, test := range tests {
+		if bs := s.blockSets[test.QualifiedName()]; bs != nil && s.local.counts(test.Pkg, pkg) {
+			merged.Merge(bs)
+		}
+	}
+
+	return merged
+}
+
+// localFunctionCoverage returns, per package, the coverage of its functions using only the
+// tests that count toward it.
+func (s *selector) localFunctionCoverage(tests []discovery.TestInfo) map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+
+	for _, pkg := range s.functionPackages() {
+		funcCov := make(map[string]float64)
+
+		for fn, cov := range s.funcMap.ComputeFunctionCoverage(s.localCoverage(pkg, tests)) {
+			if fnPkg, _ := splitFuncName(fn); fnPkg == pkg {
+				funcCov[fn] = cov
+			}
+		}
+
+		result[pkg] = funcCov
+	}
+
+	return result
+}
+
+// functionPackages returns the sorted import paths of packages in the function map.
+func (s *selector) functionPackages() []string {
+	seen := make(map[string]bool)
+
+	for file := range s.funcMap {
+		seen[path.Dir(file)] = true
+	}
+
+	return sortedKeys(seen)
+}
+
+// coverLocally keeps adding tests until every function that can reach its threshold with tests
+// from its own package (or allowlisted packages) does so with kept tests alone. Each step adds
+// the test that improves the most such functions still below threshold.
+func (s *selector) coverLocally(pools testPools, kept map[string]bool, current *coverage.BlockSet) []testResult {
+	allTests := append(append([]discovery.TestInfo(nil), pools.baseline...), pools.nonBaseline...)
+
+	// Functions each package can bring to threshold on its own
+	achievable := make(map[string][]string)
+
+	for pkg, funcCov := range s.localFunctionCoverage(allTests) {
+		for _, fn := range sortedKeys(funcCov) {
+			if funcCov[fn] >= s.thresholds.thresholdFor(fn) {
+				achievable[pkg] = append(achievable[pkg], fn)
+			}
+		}
+	}
+
+	// Local coverage of the kept tests, per package
+	keptTests := keptTestInfos(allTests, kept)
+
+	localKept := make(map[string]*coverage.BlockSet)
+	localKeptCov := make(map[string]map[string]float64)
+
+	for pkg := range achievable {
+		localKept[pkg] = s.localCoverage(pkg, keptTests)
+		localKeptCov[pkg] = s.funcMap.ComputeFunctionCoverage(localKept[pkg])
+	}
+
+	// Count achievable functions below threshold locally that a test would improve
+	localGain := func(test discovery.TestInfo) float64 {
+		testBS := s.blockSets[test.QualifiedName()]
+		improvements := 0
+
+		for pkg, funcs := range achievable {
+			if !s.local.counts(test.Pkg, pkg) {
+				continue
+			}
+
+			merged := localKept[pkg].Clone()
+			merged.Merge(testBS)
+			mergedCov := s.funcMap.ComputeFunctionCoverage(merged)
+
+			for _, fn := range funcs {
+				currentCov := localKeptCov[pkg][fn]
+				if currentCov < s.thresholds.thresholdFor(fn) && mergedCov[fn] > currentCov {
+					improvements++
+				}
+			}
+		}
+
+		return float64(improvements)
+	}
+
+	return s.greedyAdd(pools, kept, current, localGain, "local", func(test discovery.TestInfo) {
+		for pkg := range achievable {
+			if s.local.counts(test.Pkg, pkg) {
+				localKept[pkg].Merge(s.blockSets[test.QualifiedName()])
+				localKeptCov[pkg] = s.funcMap.ComputeFunctionCoverage(localKept[pkg])
+			}
+		}
+	})
+}
+
+// crossPackageFunctions returns the sorted target functions that fall below threshold with only
+// the tests that count toward their own package, given that per-package coverage.
+func (s *selector) crossPackageFunctions(targets map[string]bool, localCov map[string]map[string]float64) []string {
+	var cross []string
+
+	for _, fn := range sortedKeys(targets) {
+		pkg, _ := splitFuncName(fn)
+		if localCov[pkg][fn] < s.thresholds.thresholdFor(fn) {
+			cross = append(cross, fn)
+		}
+	}
+
+	return cross
+}
+
+// keptTestInfos returns the tests in tests that are kept.
+func keptTestInfos(tests []discovery.TestInfo, kept map[string]bool) []discovery.TestInfo {
+	var result []discovery.TestInfo
+
+	for _, test := range tests {
+		if kept[test.QualifiedName()] {
+			result = append(result, test)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,160 @@
+package testredundancy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+	"github.com/toejough/testredundancy/internal/discovery"
+)
+
+// Locality fixtures: package a has F (lines 1-10) and H (lines 11-20); package b has G.
+var localityBlocks = []string{
+	"example.com/a/a.go:1.2,1.20",
+	"example.com/a/a.go:2.2,2.20",
+	"example.com/a/a.go:11.2,11.20",
+	"example.com/b/b.go:1.2,1.20",
+}
+
+// localityBlockSet returns a profile of localityBlocks covering those at the given indexes.
+func localityBlockSet(covered ...int) *coverage.BlockSet {
+	bs := &coverage.BlockSet{Blocks: make(map[string]coverage.BlockInfo)}
+	for _, blockID := range localityBlocks {
+		bs.Blocks[blockID] = coverage.BlockInfo{Statements: 1}
+	}
+
+	for _, i := range covered {
+		bs.Blocks[localityBlocks[i]] = coverage.BlockInfo{Statements: 1, Covered: true, Count: 1}
+	}
+
+	return bs
+}
+
+// localitySelector returns a selector over the locality fixtures, where a test in package b covers
+// everything and a test in package a covers half of F.
+func localitySelector(allowlist ...string) *selector {
+	funcMap := coverage.FunctionMap{
+		"example.com/a/a.go": {
+			{Name: "F", StartLine: 1, StartCol: 1, EndLine: 10, EndCol: 2},
+			{Name: "H", StartLine: 11, StartCol: 1, EndLine: 20, EndCol: 2},
+		},
+		"example.com/b/b.go": {
+			{Name: "G", StartLine: 1, StartCol: 1, EndLine: 10, EndCol: 2},
+		},
+	}
+
+	local := &locality{allowlist: make(map[string]bool)}
+	for _, pkg := range allowlist {
+		local.allowlist[pkg] = true
+	}
+
+	return &selector{
+		funcMap: funcMap,
+		blockSets: map[string]*coverage.BlockSet{
+			"example.com/a:TestLocal": localityBlockSet(0),
+			"example.com/b:TestCross": localityBlockSet(0, 1, 2, 3),
+		},
+		thresholds: testResolver(50, 1, funcMap),
+		objective:  FunctionsObjective{},
+		local:      local,
+	}
+}
+
+// localityTests are the locality fixture tests, sorted by package, then name.
+var localityTests = []discovery.TestInfo{
+	{Pkg: "example.com/a", Name: "TestLocal"},
+	{Pkg: "example.com/b", Name: "TestCross"},
+}
+
+func TestLocalityCounts(t *testing.T) {
+	l := &locality{allowlist: map[string]bool{"example.com/testutil": true}}
+
+	tests := []struct {
+		testPkg string
+		funcPkg string
+		want    bool
+	}{
+		{"example.com/a", "example.com/a", true},
+		{"example.com/b", "example.com/a", false},
+		{"example.com/a_test", "example.com/a", false},
+		{"example.com/testutil", "example.com/a", true},
+		{"example.com/testutil", "example.com/b", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testPkg+"->"+tt.funcPkg, func(t *testing.T) {
+			if got := l.counts(tt.testPkg, tt.funcPkg); got != tt.want {
+				t.Errorf("counts(%q, %q) = %v, want %v", tt.testPkg, tt.funcPkg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoverLocally(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowlist []string
+		want      []testResult
+	}{
+		{
+			name: "own package test kept for locally achievable function",
+			want: []testResult{
+				{name: "TestCross", pkg: "example.com/b", gain: 3},
+				{name: "TestLocal", pkg: "example.com/a", gain: 1, reason: "local"},
+			},
+		},
+		{
+			name:      "allowlisted package counts as local",
+			allowlist: []string{"example.com/b"},
+			want: []testResult{
+				{name: "TestCross", pkg: "example.com/b", gain: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := localitySelector(tt.allowlist...)
+
+			got, _ := s.selectMinimal(nil, localityTests)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectMinimal() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrossPackageFunctions(t *testing.T) {
+	targets := map[string]bool{
+		"example.com/a/a.go:F": true,
+		"example.com/a/a.go:H": true,
+		"example.com/b/b.go:G": true,
+	}
+
+	tests := []struct {
+		name      string
+		allowlist []string
+		want      []string
+	}{
+		{
+			name: "function reached only by another package's test",
+			want: []string{"example.com/a/a.go:H"},
+		},
+		{
+			name:      "allowlisted tests are local",
+			allowlist: []string{"example.com/b"},
+			want:      nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := localitySelector(tt.allowlist...)
+
+			got := s.crossPackageFunctions(targets, s.localFunctionCoverage(localityTests))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("crossPackageFunctions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
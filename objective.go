@@ -0,0 +1,142 @@
+package testredundancy
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Snapshot summarizes the coverage of a set of tests, for scoring by an Objective.
+type Snapshot struct {
+	Functions  map[string]float64 // Function name -> coverage percentage
+	Statements int                // Covered statements
+	Blocks     int                // Covered blocks
+}
+
+// Objective scores how much adding a test improves coverage during greedy selection.
+// The test with the highest gain is kept; selection stops when no test has a positive gain.
+type Objective interface {
+	Name() string
+	Gain(current, merged Snapshot, threshold func(fn string) float64) float64
+}
+
+// FunctionsObjective counts functions that reach or move toward their threshold.
+// Reaching the threshold and moving slightly toward it count the same. This is the default.
+type FunctionsObjective struct{}
+
+// Name returns "functions".
+func (FunctionsObjective) Name() string { return "functions" }
+
+// Gain counts functions below threshold in current whose coverage improves in merged.
+func (FunctionsObjective) Gain(current, merged Snapshot, threshold func(fn string) float64) float64 {
+	improvements := 0
+	for fn, mergedCov := range merged.Functions {
+		if current.Functions[fn] < threshold(fn) && mergedCov > current.Functions[fn] {
+			improvements++
+		}
+	}
+	return float64(improvements)
+}
+
+// StatementsObjective counts newly covered statements.
+type StatementsObjective struct{}
+
+// Name returns "statements".
+func (StatementsObjective) Name() string { return "statements" }
+
+// Gain returns the number of statements merged covers that current does not.
+func (StatementsObjective) Gain(current, merged Snapshot, _ func(fn string) float64) float64 {
+	return float64(merged.Statements - current.Statements)
+}
+
+// BlocksObjective counts newly covered blocks.
+type BlocksObjective struct{}
+
+// Name returns "blocks".
+func (BlocksObjective) Name() string { return "blocks" }
+
+// Gain returns the number of blocks merged covers that current does not.
+func (BlocksObjective) Gain(current, merged Snapshot, _ func(fn string) float64) float64 {
+	return float64(merged.Blocks - current.Blocks)
+}
+
+// ThresholdObjective counts only functions that newly reach their threshold.
+type ThresholdObjective struct{}
+
+// Name returns "functions-strict".
+func (ThresholdObjective) Name() string { return "functions-strict" }
+
+// Gain counts functions below threshold in current that are at or above it in merged.
+func (ThresholdObjective) Gain(current, merged Snapshot, threshold func(fn string) float64) float64 {
+	reached := 0
+	for fn, mergedCov := range merged.Functions {
+		if current.Functions[fn] < threshold(fn) && mergedCov >= threshold(fn) {
+			reached++
+		}
+	}
+	return float64(reached)
+}
+
+// WeightedObjective scores each function below threshold by its progress toward the threshold
+// (as a fraction of it) times ProgressWeight, plus ReachWeight if it reaches the threshold.
+type WeightedObjective struct {
+	ReachWeight    float64
+	ProgressWeight float64
+}
+
+// Name returns "weighted".
+func (WeightedObjective) Name() string { return "weighted" }
+
+// Gain sums weighted progress and threshold crossings over functions below threshold in current.
+// Functions are summed in name order, so the float result is the same on every run.
+func (o WeightedObjective) Gain(current, merged Snapshot, threshold func(fn string) float64) float64 {
+	score := 0.0
+	for _, fn := range sortedKeys(merged.Functions) {
+		mergedCov := merged.Functions[fn]
+		currentCov := current.Functions[fn]
+		t := threshold(fn)
+		if currentCov >= t || mergedCov <= currentCov {
+			continue
+		}
+
+		if t > 0 {
+			score += o.ProgressWeight * (min(mergedCov, t) - currentCov) / t
+		}
+
+		if mergedCov >= t {
+			score += o.ReachWeight
+		}
+	}
+	return score
+}
+
+// ObjectiveNames lists the built-in objectives accepted by ObjectiveByName.
+var ObjectiveNames = []string{"functions", "statements", "blocks", "functions-strict", "weighted"}
+
+// ObjectiveByName returns the built-in objective with the given name.
+func ObjectiveByName(name string) (Objective, error) {
+	switch name {
+	case "functions":
+		return FunctionsObjective{}, nil
+	case "statements":
+		return StatementsObjective{}, nil
+	case "blocks":
+		return BlocksObjective{}, nil
+	case "functions-strict":
+		return ThresholdObjective{}, nil
+	case "weighted":
+		return WeightedObjective{ReachWeight: 1, ProgressWeight: 1}, nil
+	default:
+		return nil, fmt.Errorf("unknown objective %q (want one of %s)", name, strings.Join(ObjectiveNames, ", "))
+	}
+}
+
+// formatGain formats an objective gain, without decimals when it is a whole number.
+func formatGain(gain float64) string {
+	if gain == math.Trunc(gain) {
+		return strconv.FormatFloat(gain, 'f', 0, 64)
+	}
+
+	return strconv.FormatFloat(gain, 'f', 2, 64)
+}
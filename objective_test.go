@@ -0,0 +1,135 @@
+package testredundancy
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestObjectiveGain(t *testing.T) {
+	threshold := func(string) float64 { return 80 }
+
+	current := Snapshot{
+		Functions: map[string]float64{
+			"pkg/a.go:Reached":  90, // already at threshold
+			"pkg/a.go:Crosses":  50,
+			"pkg/a.go:Inches":   20,
+			"pkg/a.go:Flat":     40,
+			"pkg/a.go:Overshot": 60,
+		},
+		Statements: 10,
+		Blocks:     4,
+	}
+	merged := Snapshot{
+		Functions: map[string]float64{
+			"pkg/a.go:Reached":  100,
+			"pkg/a.go:Crosses":  80,
+			"pkg/a.go:Inches":   40,
+			"pkg/a.go:Flat":     40,
+			"pkg/a.go:Overshot": 100,
+			"pkg/a.go:New":      10, // not covered at all before
+		},
+		Statements: 17,
+		Blocks:     6,
+	}
+
+	tests := []struct {
+		objective Objective
+		want      float64
+	}{
+		// Crosses, Inches, Overshot and New improve while below threshold
+		{FunctionsObjective{}, 4},
+		{StatementsObjective{}, 7},
+		{BlocksObjective{}, 2},
+		// Only Crosses and Overshot reach the threshold
+		{ThresholdObjective{}, 2},
+		// Progress: Crosses 30/80, Inches 20/80, Overshot 20/80 (capped at threshold), New 10/80,
+		// plus two threshold crossings at weight 2
+		{WeightedObjective{ReachWeight: 2, ProgressWeight: 1}, 80.0/80 + 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.objective.Name(), func(t *testing.T) {
+			got := tt.objective.Gain(current, merged, threshold)
+			if got != tt.want {
+				t.Errorf("%s Gain() = %v, want %v", tt.objective.Name(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObjectiveGainNoChange(t *testing.T) {
+	threshold := func(string) float64 { return 80 }
+	snap := Snapshot{
+		Functions:  map[string]float64{"pkg/a.go:F": 50, "pkg/a.go:G": 90},
+		Statements: 5,
+		Blocks:     2,
+	}
+
+	for _, name := range ObjectiveNames {
+		objective, err := ObjectiveByName(name)
+		if err != nil {
+			t.Fatalf("ObjectiveByName(%q) error: %v", name, err)
+		}
+
+		if got := objective.Gain(snap, snap, threshold); got != 0 {
+			t.Errorf("%s Gain() with no change = %v, want 0", name, got)
+		}
+	}
+}
+
+func TestWeightedObjectiveDeterministic(t *testing.T) {
+	threshold := func(string) float64 { return 100 }
+	current := Snapshot{Functions: make(map[string]float64)}
+	merged := Snapshot{Functions: make(map[string]float64)}
+
+	// Fractions like 1/3 and 1/7 make the float sum depend on addition order
+	for i := range 50 {
+		merged.Functions[fmt.Sprintf("pkg/a.go:F%02d", i)] = 100.0 / float64(i%7+3)
+	}
+
+	objective := WeightedObjective{ReachWeight: 1, ProgressWeight: 1}
+	want := objective.Gain(current, merged, threshold)
+
+	for range 100 {
+		if got := objective.Gain(current, merged, threshold); got != want {
+			t.Fatalf("WeightedObjective Gain() = %v, then %v", want, got)
+		}
+	}
+}
+
+func TestObjectiveByName(t *testing.T) {
+	for _, name := range ObjectiveNames {
+		objective, err := ObjectiveByName(name)
+		if err != nil {
+			t.Fatalf("ObjectiveByName(%q) error: %v", name, err)
+		}
+
+		if objective.Name() != name {
+			t.Errorf("ObjectiveByName(%q).Name() = %q", name, objective.Name())
+		}
+	}
+
+	if _, err := ObjectiveByName("nope"); err == nil {
+		t.Error("ObjectiveByName(\"nope\") should fail")
+	}
+}
+
+func TestFormatGain(t *testing.T) {
+	tests := []struct {
+		gain float64
+		want string
+	}{
+		{3, "3"},
+		{0, "0"},
+		{1.5, "1.50"},
+		{2.0 / 3, "0.67"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := formatGain(tt.gain); got != tt.want {
+				t.Errorf("formatGain(%v) = %q, want %q", tt.gain, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package testredundancy
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toejough/testredundancy/internal/analysis"
+)
+
+// printParetoTable prints one row per prefix of the selection order, marking frontier prefixes.
+func printParetoTable(points []analysis.CurvePoint, targets int) {
+	fmt.Printf("  %5s %-80s %10s %9s %7s %s\n", "N", "TEST", "RUNTIME", "FUNCS", "STMTS", "FRONTIER")
+	fmt.Printf("  %5s %-80s %10s %9s %7s %s\n",
+		"-----", strings.Repeat("-", 80), "----------", "---------", "-------", "--------")
+
+	for i, p := range points {
+		frontier := ""
+		if p.Frontier {
+			frontier = "*"
+		}
+
+		fmt.Printf("  %5d %-80s %10s %9s %6.1f%% %s\n", i+1, p.Test, p.Runtime.Round(time.Millisecond),
+			fmt.Sprintf("%d/%d", p.Score, targets), p.CoveragePercent, frontier)
+	}
+}
+
+// writeParetoCSV writes the coverage-vs-runtime curve as CSV with a header row.
+func writeParetoCSV(filename string, points []analysis.CurvePoint, targets int) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filename, err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+
+	err = w.Write([]string{
+		"tests", "last_test", "runtime_seconds", "functions_at_threshold", "target_functions",
+		"statement_coverage_percent", "frontier",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+
+	for i, p := range points {
+		err := w.Write([]string{
+			strconv.Itoa(i + 1),
+			p.Test,
+			strconv.FormatFloat(p.Runtime.Seconds(), 'f', 3, 64),
+			strconv.Itoa(p.Score),
+			strconv.Itoa(targets),
+			strconv.FormatFloat(p.CoveragePercent, 'f', 2, 64),
+			strconv.FormatBool(p.Frontier),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", filename, err)
+		}
+	}
+
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package testredundancy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// writePriorityFile writes one qualified test name (pkg:TestName) per line, most valuable first.
+func writePriorityFile(filename string, order []string) error {
+	content := strings.Join(order, "\n") + "\n"
+
+	err := os.WriteFile(filename, []byte(content), 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+
+	return nil
+}
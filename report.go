@@ -0,0 +1,271 @@
+package testredundancy
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/toejough/testredundancy/internal/analysis"
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+// printEquivalenceReport prints tests with identical coverage and strict subsumption pairs.
+func printEquivalenceReport(testBlockSets map[string]*coverage.BlockSet) {
+	classes := analysis.EquivalenceClasses(testBlockSets)
+
+	fmt.Printf("\nEquivalence classes - tests with identical coverage (%d):\n", len(classes))
+	fmt.Printf("  %-80s %s\n", "TEST", "FINGERPRINT")
+	fmt.Printf("  %-80s %s\n", strings.Repeat("-", 80), "----------------")
+
+	for i, class := range classes {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		for _, name := range class.Tests {
+			fmt.Printf("  %-80s %s\n", name, class.Fingerprint)
+		}
+	}
+
+	pairs := analysis.Subsumptions(testBlockSets)
+
+	fmt.Printf("\nSubsumed tests - coverage is a strict subset of another test's (%d):\n", len(pairs))
+	fmt.Printf("  %-80s %s\n", "TEST", "SUBSUMED BY")
+	fmt.Printf("  %-80s %s\n", strings.Repeat("-", 80), strings.Repeat("-", 11))
+
+	for _, pair := range pairs {
+		fmt.Printf("  %-80s %s\n", pair.Subset, pair.Superset)
+	}
+}
+
+// printClusterReport prints clusters of highly similar tests in each package, with the
+// functions every member covers and the functions that set each member apart.
+func printClusterReport(testBlockSets map[string]*coverage.BlockSet, funcMap coverage.FunctionMap, threshold float64) {
+	clusters := analysis.Clusters(testBlockSets, threshold)
+
+	fmt.Printf("\nConsolidation candidates - clusters with %.2f+ coverage similarity (%d):\n", threshold, len(clusters))
+
+	for i, cluster := range clusters {
+		fmt.Printf("\n  Cluster %d: %s (%d tests)\n", i+1, cluster.Package, len(cluster.Tests))
+
+		// Functions covered by each member, and how many members cover each function
+		memberFuncs := make(map[string][]string)
+		funcCounts := make(map[string]int)
+
+		for _, name := range cluster.Tests {
+			funcs := funcMap.CoveredFunctions(testBlockSets[name])
+			memberFuncs[name] = funcs
+
+			for _, fn := range funcs {
+				funcCounts[fn]++
+			}
+		}
+
+		var shared []string
+		for _, fn := range memberFuncs[cluster.Tests[0]] {
+			if funcCounts[fn] == len(cluster.Tests) {
+				shared = append(shared, shortFuncName(fn))
+			}
+		}
+
+		fmt.Printf("    shared: %s\n", joinOrNone(shared))
+
+		for _, name := range cluster.Tests {
+			var differing []string
+			for _, fn := range memberFuncs[name] {
+				if funcCounts[fn] < len(cluster.Tests) {
+					differing = append(differing, shortFuncName(fn))
+				}
+			}
+
+			fmt.Printf("    %-76s +%s\n", name, joinOrNone(differing))
+		}
+	}
+}
+
+// shortFuncName trims the directory from a function key (e.g., "github.com/foo/bar.go:Foo" -> "bar.go:Foo").
+func shortFuncName(fn string) string {
+	idx := strings.LastIndex(fn, ":")
+	if idx < 0 {
+		return fn
+	}
+
+	return path.Base(fn[:idx]) + fn[idx:]
+}
+
+// joinOrNone joins names with commas, or returns "(none)" if there are none.
+func joinOrNone(names []string) string {
+	if len(names) == 0 {
+		return "(none)"
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// printAlternativeSuitesReport prints which tests were kept in every, some, or none of the
+// solutions tallied in suites.
+func printAlternativeSuitesReport(names []string, suites alternativeSuites) {
+	solutions := len(suites.sizes)
+	core, some, never := suites.classify(names)
+
+	fmt.Printf("  %d solutions, %d-%d tests each\n", solutions, slices.Min(suites.sizes), slices.Max(suites.sizes))
+
+	fmt.Printf("\nCore tests - kept in every solution (%d):\n", len(core))
+	fmt.Printf("  %-80s\n", "TEST")
+	fmt.Printf("  %-80s\n", strings.Repeat("-", 80))
+
+	for _, name := range core {
+		fmt.Printf("  %-80s\n", name)
+	}
+
+	fmt.Printf("\nTests kept in some solutions (%d):\n", len(some))
+	fmt.Printf("  %-80s %6s\n", "TEST", "KEPT")
+	fmt.Printf("  %-80s %6s\n", strings.Repeat("-", 80), "------")
+
+	for _, name := range some {
+		fmt.Printf("  %-80s %6s\n", name, fmt.Sprintf("%d/%d", suites.keptCounts[name], solutions))
+	}
+
+	fmt.Printf("\nTests never kept - redundant under all solutions (%d):\n", len(never))
+	fmt.Printf("  %-80s\n", "TEST")
+	fmt.Printf("  %-80s\n", strings.Repeat("-", 80))
+
+	for _, name := range never {
+		fmt.Printf("  %-80s\n", name)
+	}
+}
+
+// printCrossPackageReport prints functions that reach threshold only with tests from other
+// packages, with their best coverage from their own package's tests and from all tests.
+func printCrossPackageReport(funcs []string, localCov map[string]map[string]float64, totalCov map[string]float64) {
+	fmt.Printf("\nFunctions reaching threshold only with other packages' tests (%d):\n", len(funcs))
+	fmt.Printf("  %-80s %7s %7s\n", "FUNCTION", "LOCAL", "ALL")
+	fmt.Printf("  %-80s %7s %7s\n", strings.Repeat("-", 80), "-------", "-------")
+
+	for _, fn := range funcs {
+		pkg, _ := splitFuncName(fn)
+		fmt.Printf("  %-80s %6.1f%% %6.1f%%\n", fn, localCov[pkg][fn], totalCov[fn])
+	}
+}
+
+// printBaselineGapReport prints functions that reach threshold only with non-baseline tests,
+// then the statement coverage baseline tests achieve alone in each package.
+func printBaselineGapReport(
+	gaps []string,
+	baselineFuncCov, totalFuncCov map[string]float64,
+	baselineByPkg, totalByPkg map[string]coverage.StatementCounts,
+) {
+	fmt.Printf("\nFunctions reaching threshold only with non-baseline tests (%d):\n", len(gaps))
+	fmt.Printf("  %-80s %8s %7s\n", "FUNCTION", "BASELINE", "ALL")
+	fmt.Printf("  %-80s %8s %7s\n", strings.Repeat("-", 80), "--------", "-------")
+
+	for _, fn := range gaps {
+		fmt.Printf("  %-80s %7.1f%% %6.1f%%\n", fn, baselineFuncCov[fn], totalFuncCov[fn])
+	}
+
+	fmt.Printf("\nBaseline statement coverage by package (%d):\n", len(totalByPkg))
+	fmt.Printf("  %-80s %8s %7s\n", "PACKAGE", "BASELINE", "ALL")
+	fmt.Printf("  %-80s %8s %7s\n", strings.Repeat("-", 80), "--------", "-------")
+
+	for _, pkg := range sortedKeys(totalByPkg) {
+		total := totalByPkg[pkg]
+		baseline := coverage.StatementCounts{Covered: baselineByPkg[pkg].Covered, Total: total.Total}
+		fmt.Printf("  %-80s %7.1f%% %6.1f%%\n", pkg, baseline.Percent(), total.Percent())
+	}
+}
+
+// findUndertested lists functions below threshold with all tests combined, largest first.
+func findUndertested(
+	funcMap coverage.FunctionMap,
+	total *coverage.BlockSet,
+	thresholdFor func(fn string) float64,
+) []undertestedFunction {
+	uncoveredBlocks := funcMap.UncoveredBlocks(total)
+
+	var result []undertestedFunction
+
+	for fn, counts := range funcMap.FunctionStatements(total) {
+		if counts.Total == 0 || counts.Percent() >= thresholdFor(fn) {
+			continue
+		}
+
+		result = append(result, undertestedFunction{
+			Function:   fn,
+			Statements: counts.Total,
+			Coverage:   counts.Percent(),
+			Threshold:  thresholdFor(fn),
+			Uncovered:  uncoveredBlocks[fn],
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Statements != result[j].Statements {
+			return result[i].Statements > result[j].Statements
+		}
+
+		return result[i].Function < result[j].Function
+	})
+
+	return result
+}
+
+// printUndertestedReport prints functions below threshold even with all tests, then the
+// functions no test covers at all.
+func printUndertestedReport(undertested []undertestedFunction) {
+	fmt.Printf("\nUndertested functions - below threshold with all tests (%d):\n", len(undertested))
+	fmt.Printf("  %-80s %6s %7s %7s   %s\n", "FUNCTION", "STMTS", "COVER", "THRESH", "UNCOVERED BLOCKS")
+	fmt.Printf("  %-80s %6s %7s %7s   %s\n", strings.Repeat("-", 80), "------", "-------", "-------", "----------------")
+
+	var uncovered []string
+
+	for _, f := range undertested {
+		fmt.Printf("  %-80s %6d %6.1f%% %6.0f%%   %s\n",
+			f.Function, f.Statements, f.Coverage, f.Threshold, strings.Join(f.Uncovered, " "))
+
+		if f.Coverage == 0 {
+			uncovered = append(uncovered, f.Function)
+		}
+	}
+
+	fmt.Printf("\nUncovered functions - no test reaches them (%d):\n", len(uncovered))
+	fmt.Printf("  %-80s\n", "FUNCTION")
+	fmt.Printf("  %-80s\n", strings.Repeat("-", 80))
+
+	for _, fn := range uncovered {
+		fmt.Printf("  %-80s\n", fn)
+	}
+}
+
+// printHotspotReport prints the functions covered by the most tests, with the coverage
+// redundancy factor (covering tests per kept covering test).
+func printHotspotReport(hotspots []hotspot) {
+	fmt.Printf("\nOver-tested hotspots (%d):\n", len(hotspots))
+	fmt.Printf("  %-80s %6s %6s %9s %7s\n", "FUNCTION", "TESTS", "KEPT", "REDUNDANT", "FACTOR")
+	fmt.Printf("  %-80s %6s %6s %9s %7s\n", strings.Repeat("-", 80), "------", "------", "---------", "-------")
+
+	for _, h := range hotspots {
+		fmt.Printf("  %-80s %6d %6d %9d %7.1f\n", h.function, h.tests, h.kept, h.redundant, h.factor())
+	}
+}
+
+// printIntensityReport prints each test's execution intensity from hit counts. STRESS counts
+// blocks the test runs more often than any other test, so a redundant test with stress
+// blocks is the only one pushing some code hard.
+func printIntensityReport(intensities []analysis.Intensity, kept map[string]bool) {
+	fmt.Printf("\nExecution intensity (%d tests):\n", len(intensities))
+	fmt.Printf("  %-80s %9s %7s %9s %8s %8s %6s\n", "TEST", "DECISION", "BLOCKS", "HITS", "MEAN", "REPEATED", "STRESS")
+	fmt.Printf("  %-80s %9s %7s %9s %8s %8s %6s\n",
+		strings.Repeat("-", 80), "---------", "-------", "---------", "--------", "--------", "------")
+
+	for _, in := range intensities {
+		decision := "REDUNDANT"
+		if kept[in.Name] {
+			decision = "KEEP"
+		}
+
+		fmt.Printf("  %-80s %9s %7d %9d %8.1f %8d %6d\n",
+			in.Name, decision, in.CoveredBlocks, in.Hits, in.MeanHits(), in.RepeatedBlocks, in.StressBlocks)
+	}
+}
@@ -0,0 +1,70 @@
+package testredundancy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+func TestFindUndertested(t *testing.T) {
+	// F spans lines 1-10 and G lines 11-20, one block per line; H has no blocks
+	funcMap := testFuncMap("F", "G", "H")
+	g := testFile + ":G"
+
+	tests := []struct {
+		name       string
+		total      *coverage.BlockSet
+		thresholds map[string]float64 // Per-function thresholds; 80 otherwise
+		want       []undertestedFunction
+	}{
+		{
+			name:  "at threshold is not undertested",
+			total: testBlockSet(20, 1, 2, 3, 4, 5, 6, 7, 8, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20),
+		},
+		{
+			name:  "below threshold lists uncovered blocks in source order",
+			total: testBlockSet(20, 1, 2, 3, 4, 5, 6, 7, 11, 12, 13, 14, 15, 16, 17, 18),
+			want: []undertestedFunction{
+				{Function: testFile + ":F", Statements: 10, Coverage: 70, Threshold: 80, Uncovered: []string{"8.2,8.20", "9.2,9.20", "10.2,10.20"}},
+			},
+		},
+		{
+			name:       "function threshold applies",
+			total:      testBlockSet(20, 1, 2, 3, 4, 5, 6, 7, 8, 11, 12, 13, 14, 15, 16, 17, 18, 19),
+			thresholds: map[string]float64{g: 100},
+			want: []undertestedFunction{
+				{Function: g, Statements: 10, Coverage: 90, Threshold: 100, Uncovered: []string{"20.2,20.20"}},
+			},
+		},
+		{
+			name:  "more statements first, then by name",
+			total: testBlockSet(15, 1, 11),
+			want: []undertestedFunction{
+				{Function: testFile + ":F", Statements: 10, Coverage: 10, Threshold: 80, Uncovered: []string{
+					"2.2,2.20", "3.2,3.20", "4.2,4.20", "5.2,5.20", "6.2,6.20", "7.2,7.20", "8.2,8.20", "9.2,9.20", "10.2,10.20",
+				}},
+				{Function: g, Statements: 5, Coverage: 20, Threshold: 80, Uncovered: []string{
+					"12.2,12.20", "13.2,13.20", "14.2,14.20", "15.2,15.20",
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thresholdFor := func(fn string) float64 {
+				if threshold, ok := tt.thresholds[fn]; ok {
+					return threshold
+				}
+
+				return 80
+			}
+
+			got := findUndertested(funcMap, tt.total, thresholdFor)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findUndertested() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package testredundancy
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"sort"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+	"github.com/toejough/testredundancy/internal/discovery"
+)
+
+// testResult is a test's outcome in the minimal test set analysis.
+type testResult struct {
+	name       string
+	pkg        string
+	isBaseline bool
+	gain       float64
+	reason     string // Why the test was kept beyond the objective (e.g., "strict", "local", or "n-fold"), if it was
+}
+
+// selector greedily builds a minimal test set from in-memory coverage, preferring baseline tests.
+type selector struct {
+	funcMap    coverage.FunctionMap
+	blockSets  map[string]*coverage.BlockSet
+	thresholds *requirementResolver
+	objective  Objective
+	strict     bool      // Keep tests until every block covered by any test is covered
+	local      *locality // Keep tests until packages reach threshold with their own tests (nil disables)
+
+	testFuncs map[string]map[string]bool // Test -> functions it covers, computed on first use
+}
+
+// testPools are the candidate tests for greedy selection. Baseline tests are tried first.
+type testPools struct {
+	baseline    []discovery.TestInfo
+	nonBaseline []discovery.TestInfo
+}
+
+// selectMinimal keeps adding the test with the highest objective gain until no test has a
+// positive gain. Baseline tests are tried first. Ties go to the earliest test in its pool,
+// which Find sorts by package, then name, so the same coverage always yields the same selection.
+// In strict mode, tests are then added until every covered block is preserved (see coverAllBlocks),
+// then with locality until packages are self-tested (see coverLocally), and finally until
+// functions requiring several tests have them (see coverMinTests).
+// Returns the kept tests in selection order and their merged coverage.
+func (s *selector) selectMinimal(baselineTests, nonBaselineTests []discovery.TestInfo) ([]testResult, *coverage.BlockSet) {
+	pools := testPools{baseline: baselineTests, nonBaseline: nonBaselineTests}
+	kept := make(map[string]bool)
+
+	// Track current merged coverage (starts empty)
+	currentCoverage := &coverage.BlockSet{Blocks: make(map[string]coverage.BlockInfo)}
+	current := s.snapshot(currentCoverage)
+
+	// Score the merged coverage against the current selection (in-memory, by the objective)
+	objectiveGain := func(test discovery.TestInfo) float64 {
+		merged := currentCoverage.Clone()
+		merged.Merge(s.blockSets[test.QualifiedName()])
+
+		return s.objective.Gain(current, s.snapshot(merged), s.thresholds.thresholdFor)
+	}
+
+	keptTests := s.greedyAdd(pools, kept, currentCoverage, objectiveGain, "", func(discovery.TestInfo) {
+		current = s.snapshot(currentCoverage)
+	})
+
+	if s.strict {
+		keptTests = append(keptTests, s.coverAllBlocks(pools, kept, currentCoverage)...)
+	}
+
+	if s.local != nil {
+		keptTests = append(keptTests, s.coverLocally(pools, kept, currentCoverage)...)
+	}
+
+	keptTests = append(keptTests, s.coverMinTests(pools, kept, currentCoverage)...)
+
+	return keptTests, currentCoverage
+}
+
+// alternativeSuites tallies how often each test is kept across alternative minimal suites.
+type alternativeSuites struct {
+	keptCounts map[string]int // Qualified test name -> suites keeping it
+	sizes      []int          // Test count of each suite
+}
+
+// permuteSelection reruns selectMinimal permutations times with each pool shuffled, so ties
+// break differently, and tallies the resulting suites along with the already-selected kept
+// suite. Shuffles are seeded from seed and the permutation index, so results are reproducible.
+func (s *selector) permuteSelection(
+	baselineTests, nonBaselineTests []discovery.TestInfo,
+	kept map[string]bool,
+	permutations int,
+	seed uint64,
+) alternativeSuites {
+	suites := alternativeSuites{keptCounts: make(map[string]int), sizes: []int{len(kept)}}
+
+	for name := range kept {
+		suites.keptCounts[name]++
+	}
+
+	for i := range permutations {
+		rng := rand.New(rand.NewPCG(seed, uint64(i)))
+
+		baselinePool := slices.Clone(baselineTests)
+		rng.Shuffle(len(baselinePool), func(a, b int) { baselinePool[a], baselinePool[b] = baselinePool[b], baselinePool[a] })
+
+		nonBaselinePool := slices.Clone(nonBaselineTests)
+		rng.Shuffle(len(nonBaselinePool), func(a, b int) {
+			nonBaselinePool[a], nonBaselinePool[b] = nonBaselinePool[b], nonBaselinePool[a]
+		})
+
+		results, _ := s.selectMinimal(baselinePool, nonBaselinePool)
+		suites.sizes = append(suites.sizes, len(results))
+
+		for _, test := range results {
+			suites.keptCounts[test.pkg+":"+test.name]++
+		}
+	}
+
+	return suites
+}
+
+// classify splits names into tests kept in every suite (core), in some, and in none (never).
+// Core and never are sorted by name; some is sorted by how often the test was kept, most first.
+func (a alternativeSuites) classify(names []string) (core, some, never []string) {
+	for _, name := range names {
+		switch a.keptCounts[name] {
+		case len(a.sizes):
+			core = append(core, name)
+		case 0:
+			never = append(never, name)
+		default:
+			some = append(some, name)
+		}
+	}
+
+	sort.Strings(core)
+	sort.Strings(never)
+	sort.Slice(some, func(i, j int) bool {
+		if a.keptCounts[some[i]] != a.keptCounts[some[j]] {
+			return a.keptCounts[some[i]] > a.keptCounts[some[j]]
+		}
+
+		return some[i] < some[j]
+	})
+
+	return core, some, never
+}
+
+// greedyAdd keeps adding the test with the highest gain, preferring baseline tests, until no
+// test has a positive gain. Ties go to the earliest test in its pool. Each added test is marked
+// in kept, merged into current, and then passed to added (if non-nil), so gain can account for it.
+// Returns the added tests, tagged with reason.
+func (s *selector) greedyAdd(
+	pools testPools,
+	kept map[string]bool,
+	current *coverage.BlockSet,
+	gain func(discovery.TestInfo) float64,
+	reason string,
+	added func(discovery.TestInfo),
+) []testResult {
+	findBestTest := func(pool []discovery.TestInfo) (discovery.TestInfo, float64) {
+		var bestTest discovery.TestInfo
+		bestGain := 0.0
+
+		for _, test := range pool {
+			qName := test.QualifiedName()
+			if kept[qName] || s.blockSets[qName] == nil {
+				continue
+			}
+
+			if g := gain(test); g > bestGain {
+				bestGain = g
+				bestTest = test
+			}
+		}
+
+		return bestTest, bestGain
+	}
+
+	var results []testResult
+
+	for {
+		bestTest, bestGain := findBestTest(pools.baseline)
+		isBaseline := true
+
+		if bestGain <= 0 {
+			bestTest, bestGain = findBestTest(pools.nonBaseline)
+			isBaseline = false
+		}
+
+		if bestGain <= 0 {
+			return results
+		}
+
+		qName := bestTest.QualifiedName()
+		results = append(results, testResult{
+			name:       bestTest.Name,
+			pkg:        bestTest.Pkg,
+			isBaseline: isBaseline,
+			gain:       bestGain,
+			reason:     reason,
+		})
+		kept[qName] = true
+		current.Merge(s.blockSets[qName])
+
+		if added != nil {
+			added(bestTest)
+		}
+	}
+}
+
+// coverAllBlocks keeps adding the test covering the most blocks that current lacks until current
+// covers every block any test covers.
+func (s *selector) coverAllBlocks(pools testPools, kept map[string]bool, current *coverage.BlockSet) []testResult {
+	newBlocks := func(test discovery.TestInfo) float64 {
+		return float64(len(current.NewBlocksFrom(s.blockSets[test.QualifiedName()])))
+	}
+
+	return s.greedyAdd(pools, kept, current, newBlocks, "strict", nil)
+}
+
+// coverMinTests keeps adding tests until each function that requires N covering tests is covered
+// by N kept tests (or by every test that covers it, if fewer exist). Each step adds the test that
+// covers the most such functions still short of N.
+func (s *selector) coverMinTests(pools testPools, kept map[string]bool, current *coverage.BlockSet) []testResult {
+	// Count the kept tests covering each function that requires more than one
+	deficits := s.minTestDeficits(kept)
+
+	filled := func(test discovery.TestInfo) float64 {
+		count := 0
+		for fn := range s.functionsCoveredBy(test.QualifiedName()) {
+			if deficits[fn] > 0 {
+				count++
+			}
+		}
+		return float64(count)
+	}
+
+	return s.greedyAdd(pools, kept, current, filled, "n-fold", func(test discovery.TestInfo) {
+		for fn := range s.functionsCoveredBy(test.QualifiedName()) {
+			if deficits[fn] > 0 {
+				deficits[fn]--
+			}
+		}
+	})
+}
+
+// minTestDeficits returns, for each function requiring more than one covering test, how many
+// more kept tests must cover it.
+func (s *selector) minTestDeficits(kept map[string]bool) map[string]int {
+	deficits := make(map[string]int)
+
+	for _, qName := range sortedKeys(s.blockSets) {
+		for fn := range s.functionsCoveredBy(qName) {
+			if _, seen := deficits[fn]; seen {
+				continue
+			}
+
+			if n := s.thresholds.minTestsFor(fn); n > 1 {
+				deficits[fn] = n
+			}
+		}
+	}
+
+	for qName := range kept {
+		for fn := range s.functionsCoveredBy(qName) {
+			if deficits[fn] > 0 {
+				deficits[fn]--
+			}
+		}
+	}
+
+	return deficits
+}
+
+// functionsCoveredBy returns the functions a test covers at all.
+func (s *selector) functionsCoveredBy(qName string) map[string]bool {
+	if s.testFuncs == nil {
+		s.testFuncs = make(map[string]map[string]bool)
+	}
+
+	if funcs, ok := s.testFuncs[qName]; ok {
+		return funcs
+	}
+
+	funcs := make(map[string]bool)
+
+	if bs := s.blockSets[qName]; bs != nil {
+		for _, fn := range s.funcMap.CoveredFunctions(bs) {
+			funcs[fn] = true
+		}
+	}
+
+	s.testFuncs[qName] = funcs
+
+	return funcs
+}
+
+// snapshot summarizes bs for scoring by the objective.
+func (s *selector) snapshot(bs *coverage.BlockSet) Snapshot {
+	return Snapshot{
+		Functions:  s.funcMap.ComputeFunctionCoverage(bs),
+		Statements: bs.CoveredStatements(),
+		Blocks:     bs.CoveredBlockCount(),
+	}
+}
+
+// sortTests sorts tests by package, then name.
+func sortTests(tests []discovery.TestInfo) {
+	sort.Slice(tests, func(i, j int) bool {
+		if tests[i].Pkg != tests[j].Pkg {
+			return tests[i].Pkg < tests[j].Pkg
+		}
+
+		return tests[i].Name < tests[j].Name
+	})
+}
+
+// sortedKeys returns the keys of m in sorted order, for deterministic iteration.
+func sortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,328 @@
+package testredundancy
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+	"github.com/toejough/testredundancy/internal/discovery"
+)
+
+// testFile is the source file of the functions in the selector fixtures.
+const testFile = "example.com/pkg/a.go"
+
+// testFuncMap maps each named function to a ten-line range of testFile, in order: the first
+// spans lines 1-10, the second lines 11-20, and so on.
+func testFuncMap(names ...string) coverage.FunctionMap {
+	bounds := make([]coverage.FunctionBounds, 0, len(names))
+	for i, name := range names {
+		bounds = append(bounds, coverage.FunctionBounds{
+			Name:      name,
+			StartLine: i*10 + 1,
+			StartCol:  1,
+			EndLine:   i*10 + 10,
+			EndCol:    2,
+		})
+	}
+
+	return coverage.FunctionMap{testFile: bounds}
+}
+
+// testBlockSet returns a profile with one-statement blocks on lines 1 through total of testFile,
+// covering the listed lines.
+func testBlockSet(total int, covered ...int) *coverage.BlockSet {
+	bs := &coverage.BlockSet{Blocks: make(map[string]coverage.BlockInfo)}
+	for line := 1; line <= total; line++ {
+		bs.Blocks[testBlockID(line)] = coverage.BlockInfo{Statements: 1}
+	}
+
+	for _, line := range covered {
+		bs.Blocks[testBlockID(line)] = coverage.BlockInfo{Statements: 1, Covered: true, Count: 1}
+	}
+
+	return bs
+}
+
+// testBlockID returns the ID of the block on line in testFile.
+func testBlockID(line int) string {
+	return fmt.Sprintf("%s:%d.2,%d.20", testFile, line, line)
+}
+
+// testResolver returns a resolver with global requirements and already-resolved overrides.
+func testResolver(threshold float64, minTests int, funcMap coverage.FunctionMap, overrides ...resolvedOverride) *requirementResolver {
+	return newRequirementResolver(threshold, minTests, overrides, funcMap)
+}
+
+// testPool returns tests in package "pkg" with the given names.
+func testPool(names ...string) []discovery.TestInfo {
+	tests := make([]discovery.TestInfo, 0, len(names))
+	for _, name := range names {
+		tests = append(tests, discovery.TestInfo{Pkg: "pkg", Name: name})
+	}
+
+	return tests
+}
+
+func TestSelectMinimalStrict(t *testing.T) {
+	funcMap := testFuncMap("F")
+	blockSets := map[string]*coverage.BlockSet{
+		"pkg:TestMost":       testBlockSet(10, 1, 2, 3, 4, 5, 6, 7, 8),
+		"pkg:TestNine":       testBlockSet(10, 9),
+		"pkg:TestNineAndTen": testBlockSet(10, 9, 10),
+		"pkg:TestTen":        testBlockSet(10, 10),
+	}
+	pool := testPool("TestMost", "TestNine", "TestNineAndTen", "TestTen")
+
+	tests := []struct {
+		name   string
+		strict bool
+		want   []testResult
+	}{
+		{
+			name: "threshold met leaves blocks uncovered",
+			want: []testResult{{name: "TestMost", pkg: "pkg", gain: 1}},
+		},
+		{
+			name:   "strict keeps the test covering the most remaining blocks",
+			strict: true,
+			want: []testResult{
+				{name: "TestMost", pkg: "pkg", gain: 1},
+				{name: "TestNineAndTen", pkg: "pkg", gain: 2, reason: "strict"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &selector{
+				funcMap:    funcMap,
+				blockSets:  blockSets,
+				thresholds: testResolver(80, 1, funcMap),
+				objective:  FunctionsObjective{},
+				strict:     tt.strict,
+			}
+
+			got, kept := s.selectMinimal(nil, pool)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectMinimal() = %+v, want %+v", got, tt.want)
+			}
+
+			total := &coverage.BlockSet{Blocks: make(map[string]coverage.BlockInfo)}
+			for _, bs := range blockSets {
+				total.Merge(bs)
+			}
+
+			if covered := total.IsSubsetOf(kept); covered != tt.strict {
+				t.Errorf("kept suite covers every block = %v, want %v", covered, tt.strict)
+			}
+		})
+	}
+}
+
+func TestCoverAllBlocksPrefersBaseline(t *testing.T) {
+	funcMap := testFuncMap("F")
+	s := &selector{
+		funcMap: funcMap,
+		blockSets: map[string]*coverage.BlockSet{
+			"pkg:TestBaseline": testBlockSet(10, 9),
+			"pkg:TestWider":    testBlockSet(10, 9, 10),
+		},
+		thresholds: testResolver(80, 1, funcMap),
+		objective:  FunctionsObjective{},
+	}
+
+	kept := make(map[string]bool)
+	current := testBlockSet(10, 1, 2, 3, 4, 5, 6, 7, 8)
+
+	got := s.coverAllBlocks(testPools{baseline: testPool("TestBaseline"), nonBaseline: testPool("TestWider")}, kept, current)
+	want := []testResult{
+		{name: "TestBaseline", pkg: "pkg", isBaseline: true, gain: 1, reason: "strict"},
+		{name: "TestWider", pkg: "pkg", gain: 1, reason: "strict"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("coverAllBlocks() = %+v, want %+v", got, want)
+	}
+
+	if n := current.CoveredBlockCount(); n != 10 {
+		t.Errorf("current covers %d blocks, want 10", n)
+	}
+}
+
+func TestCoverMinTests(t *testing.T) {
+	// F spans lines 1-10 and G lines 11-20; each test covers one block in some of them
+	funcMap := testFuncMap("F", "G")
+	blockSets := map[string]*coverage.BlockSet{
+		"pkg:TestF":     testBlockSet(20, 1),
+		"pkg:TestFG":    testBlockSet(20, 2, 11),
+		"pkg:TestFOnly": testBlockSet(20, 3),
+		"pkg:TestG":     testBlockSet(20, 12),
+	}
+	pool := testPool("TestF", "TestFG", "TestFOnly", "TestG")
+
+	tests := []struct {
+		name         string
+		minTests     int
+		kept         []string
+		wantAdded    []testResult
+		wantDeficits map[string]int
+	}{
+		{
+			name:         "one test per function needs nothing more",
+			minTests:     1,
+			kept:         []string{"pkg:TestFG"},
+			wantDeficits: map[string]int{},
+		},
+		{
+			name:         "deficits count kept tests",
+			minTests:     2,
+			kept:         []string{"pkg:TestFG"},
+			wantDeficits: map[string]int{testFile + ":F": 1, testFile + ":G": 1},
+			wantAdded: []testResult{
+				{name: "TestF", pkg: "pkg", gain: 1, reason: "n-fold"},
+				{name: "TestG", pkg: "pkg", gain: 1, reason: "n-fold"},
+			},
+		},
+		{
+			name:         "fewer covering tests than required keeps them all",
+			minTests:     5,
+			kept:         []string{"pkg:TestF"},
+			wantDeficits: map[string]int{testFile + ":F": 4, testFile + ":G": 5},
+			wantAdded: []testResult{
+				{name: "TestFG", pkg: "pkg", gain: 2, reason: "n-fold"},
+				{name: "TestFOnly", pkg: "pkg", gain: 1, reason: "n-fold"},
+				{name: "TestG", pkg: "pkg", gain: 1, reason: "n-fold"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &selector{
+				funcMap:    funcMap,
+				blockSets:  blockSets,
+				thresholds: testResolver(80, tt.minTests, funcMap),
+				objective:  FunctionsObjective{},
+			}
+
+			kept := make(map[string]bool)
+			current := testBlockSet(20)
+
+			for _, qName := range tt.kept {
+				kept[qName] = true
+				current.Merge(blockSets[qName])
+			}
+
+			if got := s.minTestDeficits(kept); !reflect.DeepEqual(got, tt.wantDeficits) {
+				t.Errorf("minTestDeficits() = %v, want %v", got, tt.wantDeficits)
+			}
+
+			got := s.coverMinTests(testPools{nonBaseline: pool}, kept, current)
+			if !reflect.DeepEqual(got, tt.wantAdded) {
+				t.Errorf("coverMinTests() = %+v, want %+v", got, tt.wantAdded)
+			}
+
+			if len(kept) != len(tt.kept)+len(tt.wantAdded) {
+				t.Errorf("kept %d tests, want %d", len(kept), len(tt.kept)+len(tt.wantAdded))
+			}
+		})
+	}
+}
+
+func TestPermuteSelection(t *testing.T) {
+	// TestCore alone covers lines 1-6, TestA and TestB tie on 7-8, and TestNever adds nothing
+	funcMap := testFuncMap("F")
+	s := &selector{
+		funcMap: funcMap,
+		blockSets: map[string]*coverage.BlockSet{
+			"pkg:TestA":     testBlockSet(10, 7, 8),
+			"pkg:TestB":     testBlockSet(10, 7, 8),
+			"pkg:TestCore":  testBlockSet(10, 1, 2, 3, 4, 5, 6),
+			"pkg:TestNever": testBlockSet(10, 1, 2),
+		},
+		thresholds: testResolver(80, 1, funcMap),
+		objective:  BlocksObjective{},
+	}
+	pool := testPool("TestA", "TestB", "TestCore", "TestNever")
+	kept := map[string]bool{"pkg:TestA": true, "pkg:TestCore": true}
+
+	tests := []struct {
+		name         string
+		permutations int
+		seed         uint64
+	}{
+		{name: "no permutations tallies the kept suite", permutations: 0, seed: 1},
+		{name: "seed 1", permutations: 20, seed: 1},
+		{name: "seed 2", permutations: 20, seed: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.permuteSelection(nil, pool, kept, tt.permutations, tt.seed)
+			solutions := tt.permutations + 1
+
+			if again := s.permuteSelection(nil, pool, kept, tt.permutations, tt.seed); !reflect.DeepEqual(got, again) {
+				t.Errorf("permuteSelection() = %+v, then %+v with the same seed", got, again)
+			}
+
+			if want := slices.Repeat([]int{2}, solutions); !reflect.DeepEqual(got.sizes, want) {
+				t.Errorf("sizes = %v, want %v", got.sizes, want)
+			}
+
+			if n := got.keptCounts["pkg:TestCore"]; n != solutions {
+				t.Errorf("TestCore kept in %d suites, want %d", n, solutions)
+			}
+
+			if n := got.keptCounts["pkg:TestNever"]; n != 0 {
+				t.Errorf("TestNever kept in %d suites, want 0", n)
+			}
+
+			if n := got.keptCounts["pkg:TestA"] + got.keptCounts["pkg:TestB"]; n != solutions {
+				t.Errorf("TestA or TestB kept in %d suites, want %d", n, solutions)
+			}
+
+			if tt.permutations > 0 && got.keptCounts["pkg:TestB"] == 0 {
+				t.Error("TestB never kept, want shuffled pools to break the TestA/TestB tie both ways")
+			}
+		})
+	}
+}
+
+func TestAlternativeSuitesClassify(t *testing.T) {
+	names := []string{"pkg:TestA", "pkg:TestB", "pkg:TestC", "pkg:TestD", "pkg:TestE"}
+
+	tests := []struct {
+		name       string
+		keptCounts map[string]int
+		wantCore   []string
+		wantSome   []string
+		wantNever  []string
+	}{
+		{
+			name:       "kept in every suite is core, in none is never",
+			keptCounts: map[string]int{"pkg:TestA": 3, "pkg:TestB": 3, "pkg:TestC": 3},
+			wantCore:   []string{"pkg:TestA", "pkg:TestB", "pkg:TestC"},
+			wantNever:  []string{"pkg:TestD", "pkg:TestE"},
+		},
+		{
+			name:       "some sorted by kept count, then name",
+			keptCounts: map[string]int{"pkg:TestA": 1, "pkg:TestB": 2, "pkg:TestC": 1, "pkg:TestD": 3},
+			wantCore:   []string{"pkg:TestD"},
+			wantSome:   []string{"pkg:TestB", "pkg:TestA", "pkg:TestC"},
+			wantNever:  []string{"pkg:TestE"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suites := alternativeSuites{keptCounts: tt.keptCounts, sizes: []int{3, 3, 4}}
+
+			core, some, never := suites.classify(names)
+			if !slices.Equal(core, tt.wantCore) || !slices.Equal(some, tt.wantSome) || !slices.Equal(never, tt.wantNever) {
+				t.Errorf("classify() = %v, %v, %v, want %v, %v, %v", core, some, never, tt.wantCore, tt.wantSome, tt.wantNever)
+			}
+		})
+	}
+}
@@ -2,16 +2,16 @@
 package testredundancy
 
 import (
-	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/toejough/testredundancy/internal/analysis"
 	"github.com/toejough/testredundancy/internal/coverage"
 	"github.com/toejough/testredundancy/internal/discovery"
 	executil "github.com/toejough/testredundancy/internal/exec"
@@ -25,10 +25,51 @@ type BaselineTestSpec struct {
 
 // Config configures the redundant test analysis.
 type Config struct {
-	BaselineTests     []BaselineTestSpec // Tests that form the baseline coverage
-	CoverageThreshold float64            // Percentage threshold (e.g., 80.0 for 80%)
-	PackageToAnalyze  string             // Package containing tests to analyze (e.g., "./impgen/run")
-	CoveragePackages  string             // Packages to measure coverage for (e.g., "./impgen/...,./imptest/...")
+	BaselineTests      []BaselineTestSpec // Tests that form the baseline coverage
+	CoverageThreshold  float64            // Percentage threshold (e.g., 80.0 for 80%)
+	PackageToAnalyze   string             // Package containing tests to analyze (e.g., "./impgen/run")
+	CoveragePackages   string             // Packages to measure coverage for (e.g., "./impgen/...,./imptest/...")
+	ReportEquivalence  bool               // Report tests with identical coverage and strict subsumption pairs
+	ClusterThreshold   float64            // Jaccard similarity (0-1) for clustering tests to consolidate (0 disables)
+	PrioritizeFile     string             // File to write the full prioritized test order to (empty disables)
+	TimeBudget         time.Duration      // Select a smoke suite fitting this total runtime (0 disables)
+	ReportPareto       bool               // Print the coverage-vs-runtime curve over the selection order
+	ParetoCSV          string             // File to write the coverage-vs-runtime curve to as CSV (empty disables)
+	Permutations       int                // Extra tie-permuted selections to run for alternative minimal suites (0 disables)
+	Seed               uint64             // Seed for the tie-permuted selections, for reproducibility
+	Objective          Objective          // Greedy selection metric (nil uses FunctionsObjective)
+	StrictBlocks       bool               // Keep tests until the kept suite covers every block the full suite covers
+	Overrides          []Override         // Per-package and per-function threshold and MinTests overrides
+	MinTests           int                // Kept tests that must cover each function (0 or 1 for no N-fold requirement)
+	PackageLocal       bool               // Require each package's functions to reach threshold with its own tests
+	LocalAllowlist     []string           // Package patterns whose tests count toward every package under PackageLocal
+	ReportBaselineGaps bool               // Report functions only non-baseline tests bring to threshold
+	ReportUndertested  bool               // Report functions below threshold even with all tests
+	ReportHotspots     int                // Report the N functions covered by the most tests (0 disables)
+	Closures           bool               // Treat function literals as functions of their own
+	BuildTags          string             // Comma-separated build tags passed to go list and go test
+	Filters            []string           // Built-in coverage filters by name (nil applies DefaultFilters)
+	IncludeFiles       []string           // Globs of profile file paths to keep ("**" matches any directories)
+	ExcludeFiles       []string           // Globs of profile file paths to drop
+	IncludePackages    []string           // Import path patterns to keep (e.g., "github.com/foo/...")
+	ExcludePackages    []string           // Import path patterns to drop
+	IncludeGenerated   bool               // Analyze files with a "Code generated ... DO NOT EDIT." header
+	LineDirectives     bool               // Map generated Go back to //line sources (e.g., .qtpl templates) as units
+	CoverMode          string             // go test -covermode: set, count, or atomic (empty uses go test's default)
+	ReportIntensity    bool               // Report per-test execution intensity from hit counts (implies count mode)
+	LenientProfiles    bool               // Skip malformed coverage profile lines instead of failing
+	JSONReport         string             // File to write a machine-readable report to (empty disables)
+}
+
+// analysisSettings describes the config values that affect analysis results, for the run fingerprint.
+// The objective is formatted with its parameters (e.g., WeightedObjective's weights), and the
+// threshold and mintests directives in source count alongside the config requirements they beat.
+func analysisSettings(config Config, coverpkg, coverMode string, objective Objective, thresholds *requirementResolver) string {
+	return fmt.Sprintf("baseline=%v threshold=%v package=%s coverpkg=%s objective=%#v strict=%v overrides=%v mintests=%d directives=%v local=%v allowlist=%v closures=%v tags=%s filters=%v include=%v exclude=%v includepkgs=%v excludepkgs=%v generated=%v linedirectives=%v covermode=%s cluster=%v budget=%s permutations=%d seed=%d",
+		config.BaselineTests, config.CoverageThreshold, config.PackageToAnalyze, coverpkg, objective,
+		config.StrictBlocks, config.Overrides, config.MinTests, thresholds.requirementDirectives(), config.PackageLocal, config.LocalAllowlist, config.Closures, config.BuildTags,
+		config.Filters, config.IncludeFiles, config.ExcludeFiles, config.IncludePackages, config.ExcludePackages, config.IncludeGenerated, config.LineDirectives, coverMode,
+		config.ClusterThreshold, config.TimeBudget, config.Permutations, config.Seed)
 }
 
 // Find identifies unit tests that don't provide unique coverage beyond baseline tests.
@@ -43,6 +84,83 @@ func Find(config Config) error {
 		coverpkg = "./..."
 	}
 
+	var buildFlags []string
+	if config.BuildTags != "" {
+		buildFlags = []string{"-tags", config.BuildTags}
+	}
+
+	// Intensity needs hit counts, which set mode discards
+	coverMode := config.CoverMode
+	if coverMode == "" && config.ReportIntensity {
+		coverMode = coverage.ModeCount
+	}
+
+	switch coverMode {
+	case "", coverage.ModeSet, coverage.ModeCount, coverage.ModeAtomic:
+	default:
+		return fmt.Errorf("invalid cover mode %q (valid: set, count, atomic)", coverMode)
+	}
+
+	if config.ReportIntensity && coverMode == coverage.ModeSet {
+		return fmt.Errorf("intensity report needs hit counts; use cover mode count or atomic, not %s", coverMode)
+	}
+
+	filter, err := profileFilter(config)
+	if err != nil {
+		return err
+	}
+
+	readOpts := coverage.ReadOptions{Filter: filter, Lenient: config.LenientProfiles}
+
+	// In a go.work workspace, relative patterns like ./... stop at module boundaries, so
+	// expand them to every workspace module under the directory
+	analyzePatterns := []string{config.PackageToAnalyze}
+
+	modules, err := discovery.WorkspaceModules()
+	if err != nil {
+		return err
+	}
+
+	if len(modules) > 0 {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+
+		ws := workspace{dir: wd, modules: modules}
+
+		var coverPatterns []string
+		for _, pattern := range strings.Split(coverpkg, ",") {
+			coverPatterns = append(coverPatterns, ws.expand(pattern)...)
+		}
+
+		coverpkg = strings.Join(coverPatterns, ",")
+		analyzePatterns = ws.expand(config.PackageToAnalyze)
+		config = ws.expandConfig(config)
+
+		fmt.Printf("Workspace with %d modules: analyzing %s\n\n", len(modules), strings.Join(analyzePatterns, " "))
+	}
+
+	// Override and allowlist packages don't depend on coverage, so a bad pattern fails before
+	// any test runs
+	overrides, err := resolveOverrides(config.Overrides, buildFlags...)
+	if err != nil {
+		return err
+	}
+
+	var allowlist map[string]bool
+	if config.PackageLocal {
+		allowlist, err = resolveAllowlist(config.LocalAllowlist, buildFlags...)
+		if err != nil {
+			return err
+		}
+	}
+
+	objective := config.Objective
+	if objective == nil {
+		objective = FunctionsObjective{}
+	}
+
 	// Step 1: Identify baseline tests (preferred tests)
 	fmt.Println("Step 1: Identifying baseline tests...")
 	baselineTestSet := make(map[string]bool)    // key: "pkg:TestName" for exact matches
@@ -50,18 +168,20 @@ func Find(config Config) error {
 
 	for _, spec := range config.BaselineTests {
 		if spec.TestPattern != "" {
-			// Resolve package path to full module path for consistent matching
-			fullPkg, err := executil.Output(context.Background(), "go", "list", spec.Package)
+			// Resolve package paths to full import paths for consistent matching; a workspace
+			// pattern can match several packages
+			packages, err := listPackages(spec.Package, buildFlags...)
 			if err != nil {
 				return fmt.Errorf("failed to resolve package %s: %w", spec.Package, err)
 			}
 
-			fullPkg = strings.TrimSpace(fullPkg)
 			// Store pattern for prefix matching
-			baselinePatterns[fullPkg] = spec.TestPattern
+			for fullPkg := range packages {
+				baselinePatterns[fullPkg] = spec.TestPattern
+			}
 		} else {
 			// List all test functions in package
-			pkgTests, err := discovery.ListTests(spec.Package)
+			pkgTests, err := discovery.ListTests(spec.Package, buildFlags...)
 			if err != nil {
 				fmt.Printf("  Warning: couldn't list tests in %s: %v\n", spec.Package, err)
 			} else {
@@ -77,7 +197,7 @@ func Find(config Config) error {
 	// Step 2: List all tests
 	fmt.Println("\nStep 2: Listing all tests...")
 
-	allTests, err := discovery.ListTests(config.PackageToAnalyze)
+	allTests, err := discovery.ListTestsIn(analyzePatterns, buildFlags...)
 	if err != nil {
 		return fmt.Errorf("failed to list tests: %w", err)
 	}
@@ -109,6 +229,10 @@ func Find(config Config) error {
 		}
 	}
 
+	// Pool order is the greedy tie-break, so fix it independent of go list output
+	sortTests(baselineTests)
+	sortTests(nonBaselineTests)
+
 	fmt.Printf("  Found %d baseline tests, %d non-baseline tests (%d total)\n",
 		len(baselineTests), len(nonBaselineTests), len(allTests))
 
@@ -126,29 +250,55 @@ func Find(config Config) error {
 		len(parallelTests), len(allTestsToRun)-len(parallelTests))
 
 	testCoverageFiles := make(map[string]string)
+	testDurations := make(map[string]time.Duration)
 	var allTestOrder []discovery.TestInfo
+	var failedTests []discovery.TestInfo
+
+	// Arguments for running a single test with coverage
+	coverageTestArgs := func(test discovery.TestInfo, coverFileRaw string) []string {
+		args := append([]string{"test", "-count=1", "-json"}, buildFlags...)
+		if coverMode != "" {
+			args = append(args, "-covermode="+coverMode)
+		}
+		return append(args, "-coverprofile="+coverFileRaw, "-coverpkg="+coverpkg, "-run", "^"+test.Name+"$", test.Pkg)
+	}
+
+	// A test's own elapsed time, from its -json result; wall-clock time includes the build and
+	// process startup, so it is only a fallback
+	testElapsed := func(output string, test discovery.TestInfo, wall time.Duration) time.Duration {
+		if elapsed, ok := discovery.TestElapsed(strings.NewReader(output), test.Name); ok {
+			return elapsed
+		}
+		return wall
+	}
 
 	// Helper to run a single test and collect coverage
 	runSingleTest := func(test discovery.TestInfo) bool {
-		coverFile := fmt.Sprintf("cov_%s_%s.out", executil.Sanitize(filepath.Base(test.Pkg)), test.Name)
+		coverFile := fmt.Sprintf("cov_%s_%s.out", executil.Sanitize(test.Pkg), test.Name)
 		coverFileRaw := coverFile + ".raw"
 
-		testErr := executil.RunQuietCoverage("go", "test", "-count=1", "-coverprofile="+coverFileRaw, "-coverpkg="+coverpkg,
-			"-run", "^"+test.Name+"$", test.Pkg)
+		start := time.Now()
+		output, testErr := executil.OutputQuietCoverage("go", coverageTestArgs(test, coverFileRaw)...)
+		elapsed := testElapsed(output, test, time.Since(start))
 
 		if testErr != nil {
+			failedTests = append(failedTests, test)
+
 			return false
 		}
 
-		err := coverage.FilterQtpl(coverFileRaw, coverFile)
+		err := coverage.FilterFile(coverFileRaw, coverFile, readOpts)
 		if err != nil {
+			fmt.Printf("(%v) ", err)
 			os.Remove(coverFileRaw)
+			failedTests = append(failedTests, test)
 
 			return false
 		}
 
 		os.Remove(coverFileRaw)
 		testCoverageFiles[test.QualifiedName()] = coverFile
+		testDurations[test.QualifiedName()] = elapsed
 		allTestOrder = append(allTestOrder, test)
 
 		return true
@@ -202,25 +352,34 @@ func Find(config Config) error {
 				sem <- struct{}{}
 				defer func() { <-sem }()
 
-				coverFile := fmt.Sprintf("cov_%s_%s.out", executil.Sanitize(filepath.Base(test.Pkg)), test.Name)
+				coverFile := fmt.Sprintf("cov_%s_%s.out", executil.Sanitize(test.Pkg), test.Name)
 				coverFileRaw := coverFile + ".raw"
 
-				testErr := executil.RunQuietCoverage("go", "test", "-count=1", "-coverprofile="+coverFileRaw, "-coverpkg="+coverpkg,
-					"-run", "^"+test.Name+"$", test.Pkg)
+				start := time.Now()
+				output, testErr := executil.OutputQuietCoverage("go", coverageTestArgs(test, coverFileRaw)...)
+				elapsed := testElapsed(output, test, time.Since(start))
 
 				current := atomic.AddInt32(&completed, 1)
 
 				if testErr != nil {
 					fmt.Printf("    [%d/%d] %s... FAILED\n", current, len(parallelSafeTests), test.QualifiedName())
 
+					allTestOrderMu.Lock()
+					failedTests = append(failedTests, test)
+					allTestOrderMu.Unlock()
+
 					return
 				}
 
-				err := coverage.FilterQtpl(coverFileRaw, coverFile)
+				err := coverage.FilterFile(coverFileRaw, coverFile, readOpts)
 				if err != nil {
-					fmt.Printf("    [%d/%d] %s... FAILED (filter)\n", current, len(parallelSafeTests), test.QualifiedName())
+					fmt.Printf("    [%d/%d] %s... FAILED (%v)\n", current, len(parallelSafeTests), test.QualifiedName(), err)
 					os.Remove(coverFileRaw)
 
+					allTestOrderMu.Lock()
+					failedTests = append(failedTests, test)
+					allTestOrderMu.Unlock()
+
 					return
 				}
 
@@ -228,6 +387,7 @@ func Find(config Config) error {
 
 				testCoverageFilesMu.Lock()
 				testCoverageFiles[test.QualifiedName()] = coverFile
+				testDurations[test.QualifiedName()] = elapsed
 				testCoverageFilesMu.Unlock()
 
 				allTestOrderMu.Lock()
@@ -241,22 +401,51 @@ func Find(config Config) error {
 		wg.Wait()
 	}
 
+	// Parallel tests finish in arbitrary order; report in package, then name order
+	sortTests(allTestOrder)
+	sortTests(failedTests)
+
 	// Step 4: Parse coverage files into memory and build function map
 	fmt.Println("\nStep 4: Parsing coverage files and building function map...")
 
-	// Build function map from source (AST parsing)
-	funcMap, err := coverage.BuildFunctionMap(".")
+	// Build function map from the coverage packages' sources, as the build context selects them,
+	// so file keys match the paths in coverage profiles
+	sourcePkgs, err := coverage.ListPackages(strings.Split(coverpkg, ","), buildFlags...)
+	if err != nil {
+		return fmt.Errorf("failed to list coverage packages: %w", err)
+	}
+
+	funcMap, err := coverage.BuildFunctionMapFromPackages(sourcePkgs, coverage.FunctionMapOptions{
+		FuncLits:       config.Closures,
+		LineDirectives: config.LineDirectives,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to build function map: %w", err)
 	}
 
 	fmt.Printf("  Built function map with %d files\n", len(funcMap))
 
+	// Generated code only keeps tests alive for code nobody maintains by hand
+	if generated := funcMap.GeneratedFiles(); len(generated) > 0 && !config.IncludeGenerated {
+		generatedSet := make(map[string]bool, len(generated))
+		for _, file := range generated {
+			generatedSet[file] = true
+		}
+
+		readOpts.Filter = coverage.AllFilters(filter, func(file string) bool { return !generatedSet[file] })
+
+		fmt.Printf("  Excluded %d generated files from analysis:\n", len(generated))
+		for _, file := range generated {
+			fmt.Printf("    %s\n", file)
+		}
+	}
+
 	// Parse all coverage files into BlockSets (in-memory)
 	testBlockSets := make(map[string]*coverage.BlockSet)
 
-	for qName, coverFile := range testCoverageFiles {
-		bs, err := coverage.ParseFileToBlockSet(coverFile)
+	for _, qName := range sortedKeys(testCoverageFiles) {
+		coverFile := testCoverageFiles[qName]
+		bs, err := coverage.ParseFileToBlockSetWithOptions(coverFile, readOpts)
 		if err != nil {
 			fmt.Printf("  Warning: failed to parse %s: %v\n", coverFile, err)
 			continue
@@ -272,144 +461,65 @@ func Find(config Config) error {
 
 	// Compute total coverage by merging all blocks
 	totalBlockSet := &coverage.BlockSet{Blocks: make(map[string]coverage.BlockInfo)}
-	for _, bs := range testBlockSets {
-		totalBlockSet.Merge(bs)
+	for _, qName := range sortedKeys(testBlockSets) {
+		totalBlockSet.Merge(testBlockSets[qName])
 	}
 
-	// Write merged coverage to temp file and get function coverage
-	totalCoverageFile := "total_coverage_temp.out"
-	if err := coverage.WriteBlockSetToFile(totalBlockSet, totalCoverageFile); err != nil {
-		return fmt.Errorf("failed to write total coverage: %w", err)
-	}
+	// Resolve per-function requirements (directives, then overrides, then the global values)
+	thresholds := newRequirementResolver(config.CoverageThreshold, config.MinTests, overrides, funcMap)
 
-	totalFuncCoverage, err := coverage.GetAllFunctionsCoverage(totalCoverageFile)
-	os.Remove(totalCoverageFile)
-	if err != nil {
-		return fmt.Errorf("failed to get function coverage: %w", err)
+	for _, fn := range thresholds.invalidDirectives() {
+		fmt.Printf("  Warning: ignoring invalid directive on %s\n", fn)
 	}
 
-	// Identify target functions (those that reach threshold with all tests)
+	// Compute function coverage with all tests (in-memory, via the function map)
+	totalFuncCoverage := funcMap.ComputeFunctionCoverage(totalBlockSet)
+
+	// Identify target functions (those that reach their threshold with all tests)
 	targetFuncs := make(map[string]bool)
 	for fn, cov := range totalFuncCoverage {
-		if cov >= config.CoverageThreshold {
+		if cov >= thresholds.thresholdFor(fn) {
 			targetFuncs[fn] = true
 		}
 	}
 
-	fmt.Printf("  Target: %d functions at %.0f%%+ (with all tests)\n", len(targetFuncs), config.CoverageThreshold)
+	fmt.Printf("  Target: %d functions at threshold (with all tests; default %.0f%%, %d overrides)\n",
+		len(targetFuncs), config.CoverageThreshold, len(thresholds.overrides))
 
-	// Step 5: Greedy addition using function-level coverage (in-memory)
-	// Selects tests that improve the most functions toward threshold
+	// Step 5: Greedy addition using the selection objective (in-memory)
+	// Selects tests with the highest gain (by default, functions improved toward threshold)
 	fmt.Println("\nStep 5: Building minimal test set from zero (preferring baseline tests)...")
-	fmt.Printf("  %-80s %6s   %s\n", "TEST", "FUNCS", "DECISION")
+	fmt.Printf("  Objective: %s\n", objective.Name())
+	fmt.Printf("  %-80s %6s   %s\n", "TEST", "GAIN", "DECISION")
 	fmt.Printf("  %-80s %6s   %s\n", strings.Repeat("-", 80), "------", "--------")
 
-	type testResult struct {
-		name       string
-		pkg        string
-		isBaseline bool
-		gapsFilled int
+	sel := &selector{
+		funcMap:    funcMap,
+		blockSets:  testBlockSets,
+		thresholds: thresholds,
+		objective:  objective,
+		strict:     config.StrictBlocks,
 	}
 
-	var keptTests []testResult
-	keptTestFiles := []string{}
-	keptTestSet := make(map[string]bool)
-
-	// Track current merged coverage (starts empty)
-	currentCoverage := &coverage.BlockSet{Blocks: make(map[string]coverage.BlockInfo)}
-
-	// Helper to count functions that would improve toward threshold
-	countFunctionImprovements := func(currentFuncCov, mergedFuncCov map[string]float64) int {
-		improvements := 0
-		for fn, mergedCov := range mergedFuncCov {
-			currentCov := currentFuncCov[fn]
-			// Count if function improved toward threshold (below -> at/above, or any improvement if already above)
-			if currentCov < config.CoverageThreshold && mergedCov >= config.CoverageThreshold {
-				improvements++
-			} else if mergedCov > currentCov && currentCov < config.CoverageThreshold {
-				// Partial improvement toward threshold still counts (function got closer)
-				// But we weight reaching threshold more heavily
-				improvements++
-			}
-		}
-		return improvements
-	}
-
-	// Helper to find best test from a pool (in-memory, function-based counting)
-	findBestTest := func(pool []discovery.TestInfo, currentFuncCov map[string]float64) (discovery.TestInfo, int) {
-		var bestTest discovery.TestInfo
-		bestImprovements := 0
-
-		for _, test := range pool {
-			qName := test.QualifiedName()
-			if keptTestSet[qName] {
-				continue
-			}
-
-			testBS := testBlockSets[qName]
-			if testBS == nil {
-				continue
-			}
-
-			// Merge test coverage with current coverage (hypothetically)
-			merged := currentCoverage.Clone()
-			merged.Merge(testBS)
-
-			// Compute function coverage for merged
-			mergedFuncCov := funcMap.ComputeFunctionCoverage(merged)
-
-			// Count function improvements
-			improvements := countFunctionImprovements(currentFuncCov, mergedFuncCov)
-			if improvements > bestImprovements {
-				bestImprovements = improvements
-				bestTest = test
-			}
-		}
-
-		return bestTest, bestImprovements
+	if config.PackageLocal {
+		sel.local = &locality{allowlist: allowlist}
 	}
 
-	// Keep adding tests until coverage stops improving
-	// Compute initial function coverage (empty)
-	currentFuncCov := funcMap.ComputeFunctionCoverage(currentCoverage)
-
-	for {
-		// First try baseline tests
-		bestTest, improvements := findBestTest(baselineTests, currentFuncCov)
-		isBaseline := true
-
-		// If no baseline test adds coverage, try non-baseline tests
-		if improvements == 0 {
-			bestTest, improvements = findBestTest(nonBaselineTests, currentFuncCov)
-			isBaseline = false
-		}
-
-		if improvements == 0 {
-			// No test can add any new coverage
-			break
-		}
+	keptTests, currentCoverage := sel.selectMinimal(baselineTests, nonBaselineTests)
+	keptTestSet := make(map[string]bool)
 
-		// Add the best test
-		qName := bestTest.QualifiedName()
+	for _, test := range keptTests {
 		marker := ""
-		if isBaseline {
+		if test.isBaseline {
 			marker = " (baseline)"
 		}
 
-		fmt.Printf("  %-80s %6d   KEEP%s\n", qName, improvements, marker)
-
-		keptTests = append(keptTests, testResult{
-			name:       bestTest.Name,
-			pkg:        bestTest.Pkg,
-			isBaseline: isBaseline,
-			gapsFilled: improvements,
-		})
-		keptTestSet[qName] = true
-		keptTestFiles = append(keptTestFiles, testCoverageFiles[qName])
+		if test.reason != "" {
+			marker += " (" + test.reason + ")"
+		}
 
-		// Merge this test's coverage into current and update function coverage
-		currentCoverage.Merge(testBlockSets[qName])
-		currentFuncCov = funcMap.ComputeFunctionCoverage(currentCoverage)
+		fmt.Printf("  %-80s %6s   KEEP%s\n", test.pkg+":"+test.name, formatGain(test.gain), marker)
+		keptTestSet[test.pkg+":"+test.name] = true
 	}
 
 	// Mark remaining tests as redundant
@@ -441,42 +551,203 @@ func Find(config Config) error {
 		}
 	}
 
+	// Per-package coverage using only each package's own (and allowlisted) tests
+	var allLocalCov map[string]map[string]float64
+	if sel.local != nil {
+		allLocalCov = sel.localFunctionCoverage(allTestsToRun)
+	}
+
 	// Validation
 	fmt.Println("\nStep 6: Validating final coverage...")
 
-	if len(keptTestFiles) == 0 {
+	if len(keptTests) == 0 {
 		fmt.Println("  WARNING: No tests kept - validation skipped")
 	} else {
-		// Write current merged coverage to temp file and compute function coverage
-		keptCoverageFile := "kept_coverage_temp.out"
-		if err := coverage.WriteBlockSetToFile(currentCoverage, keptCoverageFile); err != nil {
-			fmt.Printf("  VALIDATION ERROR: failed to write coverage: %v\n", err)
+		// Compute function coverage of the kept tests
+		keptFuncCoverage := funcMap.ComputeFunctionCoverage(currentCoverage)
+
+		// Count how many target functions are now at threshold
+		coveredFuncs := 0
+		for fn := range targetFuncs {
+			if keptFuncCoverage[fn] >= thresholds.thresholdFor(fn) {
+				coveredFuncs++
+			}
+		}
+
+		if coveredFuncs < len(targetFuncs) {
+			fmt.Printf("  VALIDATION WARNING: Only %d/%d target functions at threshold\n",
+				coveredFuncs, len(targetFuncs))
 		} else {
-			keptFuncCoverage, err := coverage.GetAllFunctionsCoverage(keptCoverageFile)
-			os.Remove(keptCoverageFile)
+			fmt.Printf("  VALIDATION PASSED: All %d target functions maintain threshold coverage\n",
+				len(targetFuncs))
+		}
 
-			if err != nil {
-				fmt.Printf("  VALIDATION ERROR: failed to compute function coverage: %v\n", err)
+		// Strict mode promises no covered block is lost, so list any that are
+		if config.StrictBlocks {
+			lostBlocks := currentCoverage.NewBlocksFrom(totalBlockSet)
+			sort.Strings(lostBlocks)
+
+			if len(lostBlocks) > 0 {
+				fmt.Printf("  STRICT VALIDATION FAILED: %d covered blocks lost\n", len(lostBlocks))
+
+				for _, blockID := range lostBlocks {
+					fmt.Printf("    %s\n", funcMap.BlockLocation(blockID))
+				}
 			} else {
-				// Count how many target functions are now at threshold
-				coveredFuncs := 0
-				for fn := range targetFuncs {
-					if keptFuncCoverage[fn] >= config.CoverageThreshold {
-						coveredFuncs++
-					}
+				fmt.Printf("  STRICT VALIDATION PASSED: All %d covered blocks preserved\n", totalBlockSet.CoveredBlockCount())
+			}
+		}
+
+		// Locality: functions reachable with their own package's tests must stay that way
+		if sel.local != nil {
+			keptLocalCov := sel.localFunctionCoverage(keptTestInfos(allTestsToRun, keptTestSet))
+
+			achievable, localFuncs := 0, 0
+			for _, fn := range sortedKeys(targetFuncs) {
+				pkg, _ := splitFuncName(fn)
+				if allLocalCov[pkg][fn] < thresholds.thresholdFor(fn) {
+					continue
 				}
 
-				if coveredFuncs < len(targetFuncs) {
-					fmt.Printf("  VALIDATION WARNING: Only %d/%d target functions at %.0f%%+ coverage\n",
-						coveredFuncs, len(targetFuncs), config.CoverageThreshold)
-				} else {
-					fmt.Printf("  VALIDATION PASSED: All %d target functions maintain %.0f%%+ coverage\n",
-						len(targetFuncs), config.CoverageThreshold)
+				achievable++
+				if keptLocalCov[pkg][fn] >= thresholds.thresholdFor(fn) {
+					localFuncs++
 				}
 			}
+
+			if localFuncs < achievable {
+				fmt.Printf("  LOCALITY WARNING: Only %d/%d locally achievable functions at threshold with own-package tests\n",
+					localFuncs, achievable)
+			} else {
+				fmt.Printf("  LOCALITY PASSED: All %d locally achievable functions at threshold with own-package tests\n",
+					achievable)
+			}
+		}
+
+		// N-fold requirements the selection couldn't meet mean too few tests cover the function
+		deficits := sel.minTestDeficits(keptTestSet)
+		for _, fn := range sortedKeys(deficits) {
+			if deficits[fn] > 0 {
+				required := thresholds.minTestsFor(fn)
+				fmt.Printf("  N-FOLD WARNING: %s covered by %d/%d kept tests (no other tests cover it)\n",
+					fn, required-deficits[fn], required)
+			}
+		}
+	}
+
+	// Count target functions at threshold, for scoring arbitrary subsets of tests in memory
+	funcsAtThreshold := func(bs *coverage.BlockSet) int {
+		count := 0
+		for fn, cov := range funcMap.ComputeFunctionCoverage(bs) {
+			if targetFuncs[fn] && cov >= thresholds.thresholdFor(fn) {
+				count++
+			}
+		}
+		return count
+	}
+
+	// Full selection order: kept tests by marginal gain, then redundant tests by additional coverage
+	var selectionOrder []string
+	if config.PrioritizeFile != "" || config.ReportPareto || config.ParetoCSV != "" {
+		for _, test := range keptTests {
+			selectionOrder = append(selectionOrder, test.pkg+":"+test.name)
+		}
+
+		var redundantNames []string
+		for _, test := range allTestOrder {
+			if !keptTestSet[test.QualifiedName()] {
+				redundantNames = append(redundantNames, test.QualifiedName())
+			}
+		}
+
+		selectionOrder = append(selectionOrder,
+			analysis.OrderByAdditionalCoverage(currentCoverage, redundantNames, testBlockSets, testDurations)...)
+	}
+
+	if config.PrioritizeFile != "" {
+		fmt.Println("\nStep 7: Writing prioritized test order...")
+
+		// Tests that failed or timed out have no coverage to rank, but still belong in the run, first
+		var failedNames []string
+		for _, test := range failedTests {
+			failedNames = append(failedNames, test.QualifiedName())
+		}
+
+		priorityOrder := analysis.FailedFirst(failedNames, selectionOrder)
+
+		if err := writePriorityFile(config.PrioritizeFile, priorityOrder); err != nil {
+			return fmt.Errorf("failed to write prioritized order: %w", err)
+		}
+
+		fmt.Printf("  Wrote %d tests (%d failed, %d kept, %d redundant) to %s\n",
+			len(priorityOrder), len(failedNames), len(keptTests), len(selectionOrder)-len(keptTests),
+			config.PrioritizeFile)
+	}
+
+	// Smoke suite: maximize target functions at threshold within the time budget
+	if config.TimeBudget > 0 {
+		fmt.Printf("\nStep 8: Selecting smoke suite within %s budget...\n", config.TimeBudget)
+
+		var names []string
+		for _, test := range allTestOrder {
+			names = append(names, test.QualifiedName())
+		}
+
+		smoke := analysis.BudgetSelect(names, testBlockSets, testDurations, config.TimeBudget, funcsAtThreshold)
+
+		fmt.Printf("  %-80s %10s %10s\n", "TEST", "DURATION", "TOTAL")
+		fmt.Printf("  %-80s %10s %10s\n", strings.Repeat("-", 80), "----------", "----------")
+
+		smokeCoverage := &coverage.BlockSet{Blocks: make(map[string]coverage.BlockInfo)}
+		var spent time.Duration
+
+		for _, name := range smoke {
+			spent += testDurations[name]
+			smokeCoverage.Merge(testBlockSets[name])
+			fmt.Printf("  %-80s %10s %10s\n", name,
+				testDurations[name].Round(time.Millisecond), spent.Round(time.Millisecond))
+		}
+
+		fmt.Printf("  Smoke suite: %d tests, %s of %s budget, %d/%d target functions at threshold\n",
+			len(smoke), spent.Round(time.Millisecond), config.TimeBudget,
+			funcsAtThreshold(smokeCoverage), len(targetFuncs))
+	}
+
+	// Coverage-vs-runtime curve over prefixes of the selection order
+	if config.ReportPareto || config.ParetoCSV != "" {
+		fmt.Println("\nStep 9: Computing coverage-vs-runtime curve...")
+
+		points := analysis.Curve(selectionOrder, testBlockSets, testDurations,
+			totalBlockSet.TotalStatements(), funcsAtThreshold)
+
+		if config.ReportPareto {
+			printParetoTable(points, len(targetFuncs))
+		}
+
+		if config.ParetoCSV != "" {
+			if err := writeParetoCSV(config.ParetoCSV, points, len(targetFuncs)); err != nil {
+				return fmt.Errorf("failed to write pareto CSV: %w", err)
+			}
+
+			fmt.Printf("  Wrote %d rows to %s\n", len(points), config.ParetoCSV)
 		}
 	}
 
+	// Alternative minimal suites: rerun selection with shuffled pools so ties break differently
+	if config.Permutations > 0 {
+		fmt.Printf("\nStep 10: Enumerating alternative minimal suites (%d permutations, seed %d)...\n",
+			config.Permutations, config.Seed)
+
+		suites := sel.permuteSelection(baselineTests, nonBaselineTests, keptTestSet, config.Permutations, config.Seed)
+
+		var names []string
+		for _, test := range allTestOrder {
+			names = append(names, test.QualifiedName())
+		}
+
+		printAlternativeSuitesReport(names, suites)
+	}
+
 	// Clean up
 	for _, f := range testCoverageFiles {
 		os.Remove(f)
@@ -488,6 +759,10 @@ func Find(config Config) error {
 	fmt.Println("RESULTS")
 	fmt.Println("=" + strings.Repeat("=", 79))
 
+	// Identical fingerprints mean identical inputs, so report differences are real changes
+	runFingerprint := analysis.RunFingerprint(testBlockSets, analysisSettings(config, coverpkg, coverMode, objective, thresholds))
+	fmt.Printf("\nRun fingerprint: %s (%d tests)\n", runFingerprint, len(testBlockSets))
+
 	// Count kept by type
 	var keptBaseline, keptNonBaseline int
 
@@ -501,7 +776,7 @@ func Find(config Config) error {
 
 	fmt.Printf("\nTests that must be kept (%d total: %d baseline, %d non-baseline):\n",
 		len(keptTests), keptBaseline, keptNonBaseline)
-	fmt.Printf("  %-80s %6s   %s\n", "TEST", "FILLS", "TYPE")
+	fmt.Printf("  %-80s %6s   %s\n", "TEST", "GAIN", "TYPE")
 	fmt.Printf("  %-80s %6s   %s\n", strings.Repeat("-", 80), "------", "--------")
 
 	for _, test := range keptTests {
@@ -511,7 +786,11 @@ func Find(config Config) error {
 			typeStr = "baseline"
 		}
 
-		fmt.Printf("  %-80s %6d   %s\n", qName, test.gapsFilled, typeStr)
+		if test.reason != "" {
+			typeStr += " (" + test.reason + ")"
+		}
+
+		fmt.Printf("  %-80s %6s   %s\n", qName, formatGain(test.gain), typeStr)
 	}
 
 	// Trimming report - redundant baseline tests
@@ -550,6 +829,75 @@ func Find(config Config) error {
 		fmt.Printf("  %-80s\n", qName)
 	}
 
+	if sel.local != nil {
+		printCrossPackageReport(sel.crossPackageFunctions(targetFuncs, allLocalCov), allLocalCov, totalFuncCoverage)
+	}
+
+	if config.ReportBaselineGaps {
+		baselineCoverage := &coverage.BlockSet{Blocks: make(map[string]coverage.BlockInfo)}
+		for _, test := range baselineTests {
+			if bs := testBlockSets[test.QualifiedName()]; bs != nil {
+				baselineCoverage.Merge(bs)
+			}
+		}
+
+		baselineFuncCov := funcMap.ComputeFunctionCoverage(baselineCoverage)
+
+		var gaps []string
+		for _, fn := range sortedKeys(targetFuncs) {
+			if baselineFuncCov[fn] < thresholds.thresholdFor(fn) {
+				gaps = append(gaps, fn)
+			}
+		}
+
+		printBaselineGapReport(gaps, baselineFuncCov, totalFuncCoverage,
+			baselineCoverage.StatementsByPackage(), totalBlockSet.StatementsByPackage())
+	}
+
+	undertested := findUndertested(funcMap, totalBlockSet, thresholds.thresholdFor)
+
+	if config.ReportUndertested {
+		printUndertestedReport(undertested)
+	}
+
+	if config.ReportHotspots > 0 {
+		printHotspotReport(sel.hotspots(keptTestSet, config.ReportHotspots))
+	}
+
+	if config.ReportIntensity {
+		printIntensityReport(analysis.Intensities(testBlockSets), keptTestSet)
+	}
+
+	if config.ReportEquivalence {
+		printEquivalenceReport(testBlockSets)
+	}
+
+	if config.ClusterThreshold > 0 {
+		printClusterReport(testBlockSets, funcMap, config.ClusterThreshold)
+	}
+
+	if config.JSONReport != "" {
+		report := jsonReport{
+			Fingerprint: runFingerprint,
+			Kept:        toJSONTests(keptTests),
+			Redundant:   toJSONTests(append(redundantBaselineTests, redundantNonBaselineTests...)),
+			Undertested: undertested,
+			Uncovered:   []string{},
+		}
+
+		for _, f := range undertested {
+			if f.Coverage == 0 {
+				report.Uncovered = append(report.Uncovered, f.Function)
+			}
+		}
+
+		if err := writeJSONReport(config.JSONReport, report); err != nil {
+			return err
+		}
+
+		fmt.Printf("\nWrote JSON report to %s\n", config.JSONReport)
+	}
+
 	fmt.Println()
 
 	return nil
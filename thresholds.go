@@ -0,0 +1,271 @@
+package testredundancy
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+	executil "github.com/toejough/testredundancy/internal/exec"
+)
+
+// Function directive keys (e.g., "//testredundancy:threshold 100").
+const (
+	ThresholdDirective = "threshold" // Sets a function's coverage threshold percentage
+	MinTestsDirective  = "mintests"  // Sets how many kept tests must cover a function
+)
+
+// Override sets requirements for functions matching a package pattern and/or function name.
+type Override struct {
+	Package   string  `json:"package,omitempty"`   // Package pattern as for go list (e.g., "./auth/..."); empty matches all
+	Function  string  `json:"function,omitempty"`  // Function name (e.g., "Foo" or "(*T).Method"); empty matches all
+	Threshold float64 `json:"threshold,omitempty"` // Coverage threshold percentage for matching functions (0 leaves it unchanged)
+	MinTests  int     `json:"minTests,omitempty"`  // Kept tests that must cover matching functions (0 leaves it unchanged)
+}
+
+// resolvedOverride is an Override with its package pattern expanded to import paths.
+type resolvedOverride struct {
+	Override
+	packages map[string]bool // nil matches all packages
+}
+
+// requirementResolver resolves each function's coverage threshold and minimum test count.
+// Precedence, highest first: a directive on the function, an override naming the function,
+// an override for its package, then the global value. Among overrides of equal precedence,
+// the last wins.
+type requirementResolver struct {
+	globalThreshold float64
+	globalMinTests  int
+	overrides       []resolvedOverride
+	funcMap         coverage.FunctionMap
+	thresholdCache  map[string]float64
+	minTestsCache   map[string]int
+}
+
+// resolveOverrides expands the package patterns of the overrides that set a requirement with
+// go list, under the given build flags (e.g., "-tags", "integration"). Patterns don't depend on
+// coverage, so Find resolves them before running any test.
+func resolveOverrides(overrides []Override, buildFlags ...string) ([]resolvedOverride, error) {
+	var resolved []resolvedOverride
+
+	for _, o := range overrides {
+		if o.Threshold == 0 && o.MinTests == 0 {
+			continue
+		}
+
+		r, err := resolveOverride(o, buildFlags...)
+		if err != nil {
+			return nil, err
+		}
+
+		resolved = append(resolved, r)
+	}
+
+	return resolved, nil
+}
+
+// newRequirementResolver binds global requirements and resolved overrides to the function map,
+// whose directives take precedence.
+func newRequirementResolver(
+	threshold float64,
+	minTests int,
+	overrides []resolvedOverride,
+	funcMap coverage.FunctionMap,
+) *requirementResolver {
+	return &requirementResolver{
+		globalThreshold: threshold,
+		globalMinTests:  minTests,
+		overrides:       overrides,
+		funcMap:         funcMap,
+		thresholdCache:  make(map[string]float64),
+		minTestsCache:   make(map[string]int),
+	}
+}
+
+// resolveOverride expands an override's package pattern to import paths with go list.
+func resolveOverride(o Override, buildFlags ...string) (resolvedOverride, error) {
+	resolved := resolvedOverride{Override: o}
+	if o.Package == "" {
+		return resolved, nil
+	}
+
+	packages, err := listPackages(o.Package, buildFlags...)
+	if err != nil {
+		return resolvedOverride{}, fmt.Errorf("failed to resolve override package %s: %w", o.Package, err)
+	}
+
+	resolved.packages = packages
+
+	return resolved, nil
+}
+
+// listPackages expands a package pattern to the set of matching import paths with go list.
+// Build flags select the same files the tests are built with, so tagged packages match.
+func listPackages(pattern string, buildFlags ...string) (map[string]bool, error) {
+	args := append(append([]string{"list"}, buildFlags...), pattern)
+
+	out, err := executil.Output(context.Background(), "go", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	packages := make(map[string]bool)
+
+	for _, pkg := range strings.Split(strings.TrimSpace(out), "\n") {
+		if pkg != "" {
+			packages[pkg] = true
+		}
+	}
+
+	return packages, nil
+}
+
+// thresholdFor returns the coverage threshold for a function named as in the function map
+// (e.g., "github.com/foo/bar.go:Foo").
+func (r *requirementResolver) thresholdFor(fn string) float64 {
+	if t, ok := r.thresholdCache[fn]; ok {
+		return t
+	}
+
+	t := r.globalThreshold
+	if value, ok := r.directive(fn, ThresholdDirective); ok {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			r.thresholdCache[fn] = parsed
+
+			return parsed
+		}
+	}
+
+	r.matchOverrides(fn, func(o Override) bool {
+		if o.Threshold == 0 {
+			return false
+		}
+
+		t = o.Threshold
+
+		return true
+	})
+
+	r.thresholdCache[fn] = t
+
+	return t
+}
+
+// minTestsFor returns how many kept tests must cover a function named as in the function map.
+func (r *requirementResolver) minTestsFor(fn string) int {
+	if n, ok := r.minTestsCache[fn]; ok {
+		return n
+	}
+
+	n := r.globalMinTests
+	if value, ok := r.directive(fn, MinTestsDirective); ok {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			r.minTestsCache[fn] = parsed
+
+			return parsed
+		}
+	}
+
+	r.matchOverrides(fn, func(o Override) bool {
+		if o.MinTests == 0 {
+			return false
+		}
+
+		n = o.MinTests
+
+		return true
+	})
+
+	r.minTestsCache[fn] = n
+
+	return n
+}
+
+// directive returns the value of a directive on a function, if present.
+func (r *requirementResolver) directive(fn, key string) (string, bool) {
+	bounds, ok := r.funcMap.Lookup(fn)
+	if !ok {
+		return "", false
+	}
+
+	value, ok := bounds.Directives[key]
+
+	return value, ok
+}
+
+// matchOverrides calls apply for each override matching fn in config order, so later overrides
+// win. apply reports whether the override set the value; once an override naming the function
+// has, package-wide overrides no longer apply.
+func (r *requirementResolver) matchOverrides(fn string, apply func(Override) bool) {
+	pkg, name := splitFuncName(fn)
+	functionMatched := false
+
+	for _, o := range r.overrides {
+		if o.packages != nil && !o.packages[pkg] {
+			continue
+		}
+
+		switch {
+		case o.Function == name:
+			if apply(o.Override) {
+				functionMatched = true
+			}
+		case o.Function == "" && !functionMatched:
+			apply(o.Override)
+		}
+	}
+}
+
+// invalidDirectives lists functions whose threshold or mintests directive is not a number.
+func (r *requirementResolver) invalidDirectives() []string {
+	var invalid []string
+
+	for _, file := range sortedKeys(r.funcMap) {
+		for _, b := range r.funcMap[file] {
+			if value, ok := b.Directives[ThresholdDirective]; ok {
+				if _, err := strconv.ParseFloat(value, 64); err != nil {
+					invalid = append(invalid, fmt.Sprintf("%s:%s %s (%q)", file, b.Name, ThresholdDirective, value))
+				}
+			}
+
+			if value, ok := b.Directives[MinTestsDirective]; ok {
+				if _, err := strconv.Atoi(value); err != nil {
+					invalid = append(invalid, fmt.Sprintf("%s:%s %s (%q)", file, b.Name, MinTestsDirective, value))
+				}
+			}
+		}
+	}
+
+	return invalid
+}
+
+// requirementDirectives lists the threshold and mintests directives in the function map, in
+// file and source order (e.g., "github.com/foo/bar.go:Foo threshold=90").
+func (r *requirementResolver) requirementDirectives() []string {
+	var directives []string
+
+	for _, file := range sortedKeys(r.funcMap) {
+		for _, b := range r.funcMap[file] {
+			for _, key := range []string{ThresholdDirective, MinTestsDirective} {
+				if value, ok := b.Directives[key]; ok {
+					directives = append(directives, fmt.Sprintf("%s:%s %s=%s", file, b.Name, key, value))
+				}
+			}
+		}
+	}
+
+	return directives
+}
+
+// splitFuncName splits a function map name (e.g., "github.com/foo/bar.go:Foo") into
+// the function's package import path and its name.
+func splitFuncName(fn string) (pkg, name string) {
+	idx := strings.LastIndex(fn, ":")
+	if idx < 0 {
+		return "", fn
+	}
+
+	return path.Dir(fn[:idx]), fn[idx+1:]
+}
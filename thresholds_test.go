@@ -0,0 +1,169 @@
+package testredundancy
+
+import (
+	"testing"
+
+	"github.com/toejough/testredundancy/internal/coverage"
+)
+
+func TestRequirementPrecedence(t *testing.T) {
+	const fn = testFile + ":F"
+
+	pkgOnly := map[string]bool{"example.com/pkg": true}
+	otherPkg := map[string]bool{"example.com/other": true}
+
+	tests := []struct {
+		name          string
+		directives    map[string]string
+		overrides     []resolvedOverride
+		wantThreshold float64
+		wantMinTests  int
+	}{
+		{
+			name:          "global",
+			wantThreshold: 80,
+			wantMinTests:  1,
+		},
+		{
+			name: "package override beats global",
+			overrides: []resolvedOverride{
+				{Override: Override{Package: "./pkg", Threshold: 90, MinTests: 2}, packages: pkgOnly},
+			},
+			wantThreshold: 90,
+			wantMinTests:  2,
+		},
+		{
+			name: "override for another package does not apply",
+			overrides: []resolvedOverride{
+				{Override: Override{Package: "./other", Threshold: 90, MinTests: 2}, packages: otherPkg},
+			},
+			wantThreshold: 80,
+			wantMinTests:  1,
+		},
+		{
+			name: "function override beats later package override",
+			overrides: []resolvedOverride{
+				{Override: Override{Function: "F", Threshold: 95, MinTests: 3}},
+				{Override: Override{Package: "./pkg", Threshold: 90, MinTests: 2}, packages: pkgOnly},
+			},
+			wantThreshold: 95,
+			wantMinTests:  3,
+		},
+		{
+			name: "function override beats earlier package override",
+			overrides: []resolvedOverride{
+				{Override: Override{Package: "./pkg", Threshold: 90, MinTests: 2}, packages: pkgOnly},
+				{Override: Override{Function: "F", Threshold: 95, MinTests: 3}},
+			},
+			wantThreshold: 95,
+			wantMinTests:  3,
+		},
+		{
+			name: "last package override wins",
+			overrides: []resolvedOverride{
+				{Override: Override{Package: "./pkg", Threshold: 90, MinTests: 2}, packages: pkgOnly},
+				{Override: Override{Threshold: 70}},
+			},
+			wantThreshold: 70,
+			wantMinTests:  2,
+		},
+		{
+			name: "last function override wins",
+			overrides: []resolvedOverride{
+				{Override: Override{Function: "F", Threshold: 95, MinTests: 3}},
+				{Override: Override{Function: "F", Threshold: 60}},
+			},
+			wantThreshold: 60,
+			wantMinTests:  3,
+		},
+		{
+			name:       "directive beats function override",
+			directives: map[string]string{ThresholdDirective: "100", MinTestsDirective: "4"},
+			overrides: []resolvedOverride{
+				{Override: Override{Function: "F", Threshold: 95, MinTests: 3}},
+			},
+			wantThreshold: 100,
+			wantMinTests:  4,
+		},
+		{
+			name:       "invalid directive falls back to overrides",
+			directives: map[string]string{ThresholdDirective: "high"},
+			overrides: []resolvedOverride{
+				{Override: Override{Function: "F", Threshold: 95}},
+			},
+			wantThreshold: 95,
+			wantMinTests:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			funcMap := coverage.FunctionMap{testFile: {{
+				Name: "F", StartLine: 1, StartCol: 1, EndLine: 10, EndCol: 2, Directives: tt.directives,
+			}}}
+			r := testResolver(80, 1, funcMap, tt.overrides...)
+
+			if got := r.thresholdFor(fn); got != tt.wantThreshold {
+				t.Errorf("thresholdFor() = %v, want %v", got, tt.wantThreshold)
+			}
+
+			if got := r.minTestsFor(fn); got != tt.wantMinTests {
+				t.Errorf("minTestsFor() = %v, want %v", got, tt.wantMinTests)
+			}
+		})
+	}
+}
+
+func TestSplitFuncName(t *testing.T) {
+	tests := []struct {
+		fn       string
+		wantPkg  string
+		wantName string
+	}{
+		{"example.com/pkg/a.go:F", "example.com/pkg", "F"},
+		{"example.com/pkg/a.go:(*T).Method", "example.com/pkg", "(*T).Method"},
+		{"F", "", "F"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fn, func(t *testing.T) {
+			pkg, name := splitFuncName(tt.fn)
+			if pkg != tt.wantPkg || name != tt.wantName {
+				t.Errorf("splitFuncName(%q) = %q, %q, want %q, %q", tt.fn, pkg, name, tt.wantPkg, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestAnalysisSettings(t *testing.T) {
+	settings := func(objective Objective, directives map[string]string) string {
+		funcMap := coverage.FunctionMap{testFile: {{
+			Name: "F", StartLine: 1, StartCol: 1, EndLine: 10, EndCol: 2, Directives: directives,
+		}}}
+
+		return analysisSettings(Config{}, "./...", "set", objective, testResolver(80, 1, funcMap))
+	}
+
+	base := settings(WeightedObjective{ReachWeight: 1, ProgressWeight: 1}, nil)
+
+	tests := []struct {
+		name     string
+		settings string
+	}{
+		{"objective parameters", settings(WeightedObjective{ReachWeight: 2, ProgressWeight: 1}, nil)},
+		{"threshold directive", settings(WeightedObjective{ReachWeight: 1, ProgressWeight: 1}, map[string]string{ThresholdDirective: "90"})},
+		{"mintests directive", settings(WeightedObjective{ReachWeight: 1, ProgressWeight: 1}, map[string]string{MinTestsDirective: "2"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.settings == base {
+				t.Errorf("analysisSettings() unchanged by %s: %s", tt.name, base)
+			}
+		})
+	}
+
+	if again := settings(WeightedObjective{ReachWeight: 1, ProgressWeight: 1}, nil); again != base {
+		t.Errorf("analysisSettings() = %q, then %q for the same inputs", base, again)
+	}
+}
@@ -0,0 +1,61 @@
+package testredundancy
+
+import (
+	"github.com/toejough/testredundancy/internal/discovery"
+)
+
+// workspace expands relative package patterns across the modules of a go.work workspace, where
+// patterns like ./... stop at module boundaries. Without modules, patterns are unchanged.
+type workspace struct {
+	dir     string // Directory relative patterns are resolved against
+	modules []discovery.Module
+}
+
+// expand returns the patterns covering pattern's packages in every workspace module.
+func (w workspace) expand(pattern string) []string {
+	if len(w.modules) == 0 {
+		return []string{pattern}
+	}
+
+	return discovery.ExpandWorkspacePattern(pattern, w.dir, w.modules)
+}
+
+// expandConfig returns config with its baseline, override and allowlist package patterns
+// expanded. A baseline spec or override whose pattern expands to several keeps its settings
+// for each, in place, so override order (and with it precedence) is preserved.
+func (w workspace) expandConfig(config Config) Config {
+	var baselines []BaselineTestSpec
+
+	for _, spec := range config.BaselineTests {
+		for _, pattern := range w.expand(spec.Package) {
+			spec.Package = pattern
+			baselines = append(baselines, spec)
+		}
+	}
+
+	var overrides []Override
+
+	for _, o := range config.Overrides {
+		if o.Package == "" {
+			overrides = append(overrides, o)
+			continue
+		}
+
+		for _, pattern := range w.expand(o.Package) {
+			o.Package = pattern
+			overrides = append(overrides, o)
+		}
+	}
+
+	var allowlist []string
+
+	for _, pattern := range config.LocalAllowlist {
+		allowlist = append(allowlist, w.expand(pattern)...)
+	}
+
+	config.BaselineTests = baselines
+	config.Overrides = overrides
+	config.LocalAllowlist = allowlist
+
+	return config
+}
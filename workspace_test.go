@@ -0,0 +1,73 @@
+package testredundancy
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/toejough/testredundancy/internal/discovery"
+)
+
+func TestWorkspaceExpandConfig(t *testing.T) {
+	dir := filepath.FromSlash("/ws")
+	modules := []discovery.Module{
+		{Path: "example.com/api", Dir: filepath.Join(dir, "api")},
+		{Path: "example.com/web", Dir: filepath.Join(dir, "web")},
+	}
+
+	config := Config{
+		BaselineTests: []BaselineTestSpec{
+			{Package: "./...", TestPattern: "TestUAT"},
+			{Package: "./web/..."},
+			{Package: "./api"},
+		},
+		Overrides: []Override{
+			{Package: "./...", MinTests: 2},
+			{Function: "Init", Threshold: 50},
+			{Package: "./api/...", Function: "Serve", Threshold: 90},
+		},
+		LocalAllowlist: []string{"./...", "example.com/shared"},
+	}
+
+	tests := []struct {
+		name    string
+		modules []discovery.Module
+		want    Config
+	}{
+		{
+			name:    "no modules leaves patterns unchanged",
+			modules: nil,
+			want:    config,
+		},
+		{
+			name:    "modules expand baseline, override and allowlist patterns",
+			modules: modules,
+			want: Config{
+				BaselineTests: []BaselineTestSpec{
+					{Package: "example.com/api/...", TestPattern: "TestUAT"},
+					{Package: "example.com/web/...", TestPattern: "TestUAT"},
+					{Package: "example.com/web/..."},
+					{Package: "./api"},
+				},
+				Overrides: []Override{
+					{Package: "example.com/api/...", MinTests: 2},
+					{Package: "example.com/web/...", MinTests: 2},
+					{Function: "Init", Threshold: 50},
+					{Package: "example.com/api/...", Function: "Serve", Threshold: 90},
+				},
+				LocalAllowlist: []string{"example.com/api/...", "example.com/web/...", "example.com/shared"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := workspace{dir: dir, modules: tt.modules}
+
+			got := w.expandConfig(config)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}